@@ -3,10 +3,14 @@ package main
 import (
 	"context"
 	"encoding/gob"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/alexedwards/scs/pgxstore"
@@ -27,7 +31,9 @@ func main() {
 		slog.Warn("Something happened with godotenv")
 	}
 
-	ctx := context.Background()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	pool, err := pgxpool.New(ctx, fmt.Sprintf("user=%s password=%s host=%s port=%s, dbname=%s",
 		os.Getenv("GOBID_DATABASE_USER"),
 		os.Getenv("GOBID_DATABASE_PASSWORD"),
@@ -51,30 +57,118 @@ func main() {
 	s.Cookie.HttpOnly = true
 	s.Cookie.SameSite = http.SameSiteLaxMode
 
+	// com uma única instância o bus em memória dispensa o LISTEN/NOTIFY
+	var eventBus services.EventBus = services.NewMemoryEventBus()
+	if os.Getenv("GOBID_EVENT_BUS") != "memory" {
+		pgEventBus := services.NewPgEventBus(pool)
+		go pgEventBus.Listen(ctx)
+		eventBus = pgEventBus
+	}
+
 	api := api.Api{
 		Router:   chi.NewMux(),
 		Sessions: s,
 		WsUpgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool { return true }, // é tru só em tempo de DEV
+			CheckOrigin:  func(r *http.Request) bool { return true }, // é tru só em tempo de DEV
+			Subprotocols: []string{services.ProtocolV1},
 		},
 
 		UserService:    services.NewUserService(pool),
 		ProductService: services.NewProductService(pool),
-		BidService:     services.NewBidService(pool),
+		BidService: services.NewBidService(pool, services.SoftClose{
+			Window:    durationFromEnv("GOBID_SOFT_CLOSE_WINDOW", 2*time.Minute),
+			Extension: durationFromEnv("GOBID_SOFT_CLOSE_EXTENSION", 2*time.Minute),
+		}, intFromEnv("GOBID_BUY_NOW_THRESHOLD", 50)),
+		IdempotencyService: services.NewIdempotencyService(pool),
 		AuctionLobby: services.AuctionLobby{
 			Rooms: make(map[uuid.UUID]*services.AuctionRoom),
 		},
+		EventBus: eventBus,
 	}
 
 	api.BindRoutes()
 
+	// falhas de um produto só vão para o log e não impedem a subida
+	settled, err := api.BidService.SettleExpiredAuctions(ctx)
+	if err != nil {
+		slog.Error("Failed to settle expired auctions", "error", err)
+	}
+	slog.Info("Expired auctions settled", "count", len(settled))
+
+	if err := api.RestoreAuctionRooms(ctx); err != nil {
+		panic(err)
+	}
+
+	go api.RunAuctionScheduler(ctx, durationFromEnv("GOBID_SCHEDULER_INTERVAL", 5*time.Second))
+
 	port := os.Getenv("GOBID_APP_PORT")
 	if port == "" {
 		port = "8080"
 	}
 
-	fmt.Printf("Starting Server on port :%s\n", port)
-	if err := http.ListenAndServe(":"+port, api.Router); err != nil {
-		panic(err)
+	server := &http.Server{Addr: ":" + port, Handler: api.Router}
+
+	go func() {
+		fmt.Printf("Starting Server on port :%s\n", port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			panic(err)
+		}
+	}()
+
+	// métricas só no listener interno, nunca na porta pública
+	debugAddr := os.Getenv("GOBID_DEBUG_ADDR")
+	if debugAddr == "" {
+		debugAddr = "127.0.0.1:6060"
+	}
+
+	debugServer := &http.Server{Addr: debugAddr, Handler: api.DebugRouter()}
+
+	go func() {
+		if err := debugServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Debug server failed", "error", err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	slog.Info("Shutting down server")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), durationFromEnv("GOBID_SHUTDOWN_TIMEOUT", 15*time.Second))
+	defer cancel()
+
+	if err := api.Shutdown(shutdownCtx, server); err != nil {
+		slog.Error("Graceful shutdown did not finish", "error", err)
+	}
+
+	if err := debugServer.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Debug server shutdown did not finish", "error", err)
+	}
+}
+
+func durationFromEnv(key string, fallback time.Duration) time.Duration {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		slog.Warn("Invalid duration, using default", "key", key, "value", raw, "default", fallback)
+		return fallback
+	}
+	return d
+}
+
+func intFromEnv(key string, fallback int64) int64 {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+
+	v, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		slog.Warn("Invalid number, using default", "key", key, "value", raw, "default", fallback)
+		return fallback
 	}
+	return v
 }
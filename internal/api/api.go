@@ -1,6 +1,10 @@
 package api
 
 import (
+	"context"
+	"net/http"
+	"sync"
+
 	"github.com/alexedwards/scs/v2"
 	"github.com/go-chi/chi/v5"
 	"github.com/gorilla/websocket"
@@ -12,8 +16,68 @@ type Api struct {
 	Sessions     *scs.SessionManager
 	WsUpgrader   websocket.Upgrader
 	AuctionLobby services.AuctionLobby
+	EventBus     services.EventBus
 
 	UserService    services.UserService
 	ProductService services.ProductService
 	BidService     services.BidService
+
+	IdempotencyService services.IdempotencyService
+
+	// conexões websocket abertas; o http.Server não acompanha conexões
+	// sequestradas pelo upgrade
+	wsConnections sync.WaitGroup
+}
+
+// Shutdown para de aceitar conexões, fecha as salas (os pedidos que elas estão
+// processando terminam antes), espera as requisições em andamento e os
+// clientes websocket receberem o close frame. As salas fecham junto com o
+// início do shutdown porque os streams SSE só terminam quando a sala fecha.
+func (api *Api) Shutdown(ctx context.Context, server *http.Server) error {
+	roomsClosed := make(chan error, 1)
+	server.RegisterOnShutdown(func() {
+		roomsClosed <- api.closeAuctionRooms(ctx)
+	})
+
+	if err := server.Shutdown(ctx); err != nil {
+		return err
+	}
+
+	select {
+	case err := <-roomsClosed:
+		if err != nil {
+			return err
+		}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		api.wsConnections.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (api *Api) closeAuctionRooms(ctx context.Context) error {
+	api.AuctionLobby.Lock()
+	rooms := make([]*services.AuctionRoom, 0, len(api.AuctionLobby.Rooms))
+	for _, room := range api.AuctionLobby.Rooms {
+		rooms = append(rooms, room)
+	}
+	api.AuctionLobby.Unlock()
+
+	for _, room := range rooms {
+		if err := room.Close(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mauvalente/go-bid/internal/jsonutils"
+	"github.com/mauvalente/go-bid/internal/services"
+)
+
+// sseKeepAlive mantém a conexão viva em proxies que derrubam streams parados.
+const sseKeepAlive = 15 * time.Second
+
+// handleAuctionEvents é a alternativa ao websocket para quem está atrás de
+// proxies que bloqueiam o upgrade. Os eventos são os mesmos da sala, no
+// envelope do gobid.v1, e o id de cada evento é a sequência usada pelo
+// Last-Event-ID.
+func (api *Api) handleAuctionEvents(w http.ResponseWriter, r *http.Request) {
+	productId, err := uuid.Parse(chi.URLParam(r, "product_id"))
+	if err != nil {
+		jsonutils.EncodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"message": "invalid product id, must be a valid id",
+		})
+		return
+	}
+
+	rawSince := r.Header.Get("Last-Event-ID")
+	if rawSince == "" {
+		rawSince = r.URL.Query().Get("since")
+	}
+	since, err := parseSince(rawSince)
+	if err != nil {
+		jsonutils.EncodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"message": "invalid Last-Event-ID, must be a non-negative sequence number",
+		})
+		return
+	}
+
+	userId, ok := api.Sessions.Get(r.Context(), "AuthenticatedUserId").(uuid.UUID)
+	if !ok {
+		jsonutils.EncodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later",
+		})
+		return
+	}
+
+	product, err := api.ProductService.GetProductById(r.Context(), productId)
+	if err != nil {
+		if errors.Is(err, services.ErrProductNotFound) {
+			jsonutils.EncodeJson(w, r, http.StatusNotFound, map[string]any{
+				"error": "no product with given id",
+			})
+			return
+		}
+		jsonutils.EncodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later",
+		})
+		return
+	}
+
+	room, ok := api.openAuctionRoom(product)
+	if !ok {
+		jsonutils.EncodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"message": "the auction has ended",
+		})
+		return
+	}
+
+	client := services.NewClient(room, nil, userId)
+	client.LastSeq = since
+	client.Protocol = services.ProtocolV1
+
+	if !client.Join() {
+		jsonutils.EncodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"message": "the auction has ended",
+		})
+		return
+	}
+	defer client.Leave()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// o writer da sessão só expõe o Flush pelo Unwrap
+	rc := http.NewResponseController(w)
+	if err := rc.Flush(); err != nil {
+		slog.Error("Streaming is not supported", "error", err)
+		return
+	}
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case message, ok := <-client.Send:
+			if !ok {
+				code, reason := client.CloseReason()
+				data, _ := json.Marshal(map[string]any{"code": code, "reason": reason})
+				fmt.Fprintf(w, "event: close\ndata: %s\n\n", data)
+				rc.Flush()
+				return
+			}
+
+			if err := writeEvent(w, message); err != nil {
+				slog.Error("Failed to write auction event", "auctionId", productId, "user_id", userId, "error", err)
+				return
+			}
+			rc.Flush()
+
+			if message.Kind == services.AuctionFinished {
+				return
+			}
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			rc.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, m services.Message) error {
+	envelope, err := services.EncodeEnvelope(m)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+
+	if m.Seq > 0 {
+		fmt.Fprintf(w, "id: %d\n", m.Seq)
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", envelope.Type, data)
+	return err
+}
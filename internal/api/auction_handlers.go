@@ -1,13 +1,19 @@
 package api
 
 import (
+	"context"
 	"errors"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
 	"github.com/mauvalente/go-bid/internal/jsonutils"
 	"github.com/mauvalente/go-bid/internal/services"
+	"github.com/mauvalente/go-bid/internal/store/pgstore"
 )
 
 func (api *Api) handleSubscribeUserToAuction(w http.ResponseWriter, r *http.Request) {
@@ -21,7 +27,7 @@ func (api *Api) handleSubscribeUserToAuction(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	_, err = api.ProductService.GetProductById(r.Context(), productId)
+	product, err := api.ProductService.GetProductById(r.Context(), productId)
 	if err != nil {
 		if errors.Is(err, services.ErrProductNotFound) {
 			jsonutils.EncodeJson(w, r, http.StatusNotFound, map[string]any{
@@ -35,6 +41,14 @@ func (api *Api) handleSubscribeUserToAuction(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	since, err := parseSince(r.URL.Query().Get("since"))
+	if err != nil {
+		jsonutils.EncodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"message": "invalid since, must be a non-negative sequence number",
+		})
+		return
+	}
+
 	userId, ok := api.Sessions.Get(r.Context(), "AuthenticatedUserId").(uuid.UUID)
 	if !ok {
 		jsonutils.EncodeJson(w, r, http.StatusInternalServerError, map[string]any{
@@ -43,9 +57,7 @@ func (api *Api) handleSubscribeUserToAuction(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	api.AuctionLobby.Lock()
-	room, ok := api.AuctionLobby.Rooms[productId]
-	api.AuctionLobby.Unlock()
+	room, ok := api.openAuctionRoom(product)
 	if !ok {
 		jsonutils.EncodeJson(w, r, http.StatusBadRequest, map[string]any{
 			"message": "the auction has ended",
@@ -62,13 +74,197 @@ func (api *Api) handleSubscribeUserToAuction(w http.ResponseWriter, r *http.Requ
 	}
 
 	client := services.NewClient(room, conn, userId)
+	client.LastSeq = since
+	client.Protocol = conn.Subprotocol()
 
-	room.Register <- client
+	api.wsConnections.Add(1)
+	defer api.wsConnections.Done()
+
+	if !client.Join() {
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "the auction has ended"))
+		conn.Close()
+		return
+	}
+
+	// o handler só retorna quando a conexão termina, para que o shutdown
+	// consiga esperar os close frames serem enviados
 	go client.ReadEventLoop()
-	go client.WriteEventLoop()
+	client.WriteEventLoop()
+}
+
+func (api *Api) handleBuyNow(w http.ResponseWriter, r *http.Request) {
+	rawProductId := chi.URLParam(r, "product_id")
+
+	productId, err := uuid.Parse(rawProductId)
+	if err != nil {
+		jsonutils.EncodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"message": "invalid product id, must be a valid id",
+		})
+		return
+	}
+
+	userId, ok := api.Sessions.Get(r.Context(), "AuthenticatedUserId").(uuid.UUID)
+	if !ok {
+		jsonutils.EncodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later",
+		})
+		return
+	}
 
-	for {
+	product, err := api.ProductService.GetProductById(r.Context(), productId)
+	if err != nil {
+		if errors.Is(err, services.ErrProductNotFound) {
+			jsonutils.EncodeJson(w, r, http.StatusNotFound, map[string]any{
+				"error": "no product with given id",
+			})
+			return
+		}
+		jsonutils.EncodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later",
+		})
+		return
+	}
 
+	room, ok := api.openAuctionRoom(product)
+	if !ok {
+		jsonutils.EncodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"message": "the auction has ended",
+		})
+		return
 	}
 
+	reply, err := room.Submit(r.Context(), services.Message{Kind: services.BuyNow, UserId: userId})
+	if err != nil {
+		if errors.Is(err, services.ErrAuctionRoomClosed) {
+			if api.auctionEnded(r.Context(), productId) {
+				jsonutils.EncodeJson(w, r, http.StatusBadRequest, map[string]any{
+					"message": "the auction has ended",
+				})
+				return
+			}
+			jsonutils.EncodeJson(w, r, http.StatusServiceUnavailable, map[string]any{
+				"error": "the auction is temporarily unavailable, try again later",
+			})
+			return
+		}
+		jsonutils.EncodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later",
+		})
+		return
+	}
+
+	if reply.Kind == services.FailedToBuyNow {
+		jsonutils.EncodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"error": reply.Message,
+		})
+		return
+	}
+
+	jsonutils.EncodeJson(w, r, http.StatusOK, map[string]any{
+		"message":    reply.Message,
+		"product_id": productId,
+		"buyer_id":   reply.UserId,
+		"amount":     reply.Amount,
+	})
+}
+
+// auctionEnded relê o produto depois que a sala recusou um pedido: a sala
+// fecha tanto no fim do leilão quanto no shutdown. Na dúvida (erro ao ler),
+// o leilão é tratado como aberto.
+func (api *Api) auctionEnded(ctx context.Context, productId uuid.UUID) bool {
+	product, err := api.ProductService.GetProductById(ctx, productId)
+	if err != nil {
+		slog.Error("Failed to reload product", "productId", productId, "error", err)
+		return false
+	}
+	return product.SettledAt.Valid || !time.Now().Before(product.AuctionEnd)
+}
+
+// openAuctionRoom devolve a sala local do leilão. Com várias instâncias o
+// leilão pode ter sido criado em outra, então a sala é aberta aqui sob demanda
+// enquanto o leilão estiver aberto no banco.
+func (api *Api) openAuctionRoom(product pgstore.Product) (*services.AuctionRoom, bool) {
+	api.AuctionLobby.Lock()
+	defer api.AuctionLobby.Unlock()
+
+	if room, ok := api.AuctionLobby.Rooms[product.ID]; ok {
+		return room, true
+	}
+
+	if product.SettledAt.Valid || !time.Now().Before(product.AuctionEnd) {
+		return nil, false
+	}
+
+	return api.runAuctionRoom(product), true
+}
+
+func (api *Api) startAuctionRoom(product pgstore.Product) *services.AuctionRoom {
+	api.AuctionLobby.Lock()
+	defer api.AuctionLobby.Unlock()
+
+	return api.runAuctionRoom(product)
+}
+
+// runAuctionRoom espera que o lock do lobby já esteja com quem chamou.
+func (api *Api) runAuctionRoom(product pgstore.Product) *services.AuctionRoom {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	auctionRoom := services.NewAuctionRoom(ctx, product, api.BidService, api.EventBus)
+	productId := product.ID
+
+	api.AuctionLobby.Rooms[productId] = auctionRoom
+
+	go func() {
+		defer cancel()
+		auctionRoom.Run()
+
+		api.AuctionLobby.Lock()
+		if api.AuctionLobby.Rooms[productId] == auctionRoom {
+			delete(api.AuctionLobby.Rooms, productId)
+		}
+		api.AuctionLobby.Unlock()
+	}()
+
+	return auctionRoom
+}
+
+// RestoreAuctionRooms recria em memória as salas dos leilões que ainda estão
+// abertos no banco, para que um restart não encerre os leilões em andamento.
+func (api *Api) RestoreAuctionRooms(ctx context.Context) error {
+	products, err := api.ProductService.GetAllAvailableProducts(ctx)
+	if err != nil {
+		if errors.Is(err, services.ErrProductNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	restored := 0
+	for _, product := range products {
+		if product.IsSold {
+			continue
+		}
+		api.startAuctionRoom(product)
+		restored++
+	}
+
+	slog.Info("Auction rooms restored", "count", restored)
+	return nil
+}
+
+// parseSince lê a última sequência recebida antes de reconectar; vazio é uma
+// conexão nova.
+func parseSince(raw string) (int64, error) {
+	if raw == "" {
+		return 0, nil
+	}
+
+	since, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if since < 0 {
+		return 0, strconv.ErrRange
+	}
+	return since, nil
 }
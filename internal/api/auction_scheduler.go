@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/mauvalente/go-bid/internal/services"
+)
+
+// RunAuctionScheduler abre os leilões agendados. Como o estado fica no banco,
+// a primeira rodada logo após o boot recupera os inícios perdidos enquanto o
+// servidor estava fora. Também aproveita a rodada para liquidar os leilões
+// vencidos que nenhuma sala liquidou e para apagar as chaves de idempotência
+// vencidas.
+func (api *Api) RunAuctionScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		api.startDueAuctions(ctx)
+		api.settleExpiredAuctions(ctx)
+		api.deleteExpiredIdempotencyKeys(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (api *Api) startDueAuctions(ctx context.Context) {
+	started, err := api.ProductService.StartDueAuctions(ctx)
+	if err != nil {
+		slog.Error("Failed to start scheduled auctions", "error", err)
+		return
+	}
+
+	// só a instância que virou o status recebe o id, e o bus avisa as salas
+	// de todas as outras
+	for _, productId := range started {
+		slog.Info("Scheduled auction has started", "auctionId", productId)
+
+		err := api.EventBus.Publish(ctx, services.Event{
+			Type:      services.EventAuctionStarted,
+			AuctionId: productId,
+			Message:   services.Message{Kind: services.AuctionStarted, Message: "The auction has started"},
+		})
+		if err != nil {
+			slog.Error("Failed to publish auction start", "auctionId", productId, "error", err)
+		}
+	}
+}
+
+// settleExpiredAuctions cobre o leilão cuja única sala morreu com a instância:
+// o resultado vai pelo bus para as salas que ainda estiverem abertas.
+func (api *Api) settleExpiredAuctions(ctx context.Context) {
+	settled, err := api.BidService.SettleExpiredAuctions(ctx)
+	if err != nil {
+		slog.Error("Failed to settle expired auctions", "error", err)
+		return
+	}
+
+	for _, result := range settled {
+		slog.Info("Expired auction settled", "auctionId", result.ProductId)
+
+		for _, event := range result.FinishedEvents() {
+			if err := api.EventBus.Publish(ctx, event); err != nil {
+				slog.Error("Failed to publish auction result", "auctionId", result.ProductId, "error", err)
+			}
+		}
+	}
+}
+
+func (api *Api) deleteExpiredIdempotencyKeys(ctx context.Context) {
+	deleted, err := api.IdempotencyService.DeleteExpired(ctx)
+	if err != nil {
+		slog.Error("Failed to delete expired idempotency keys", "error", err)
+		return
+	}
+	if deleted > 0 {
+		slog.Info("Expired idempotency keys deleted", "count", deleted)
+	}
+}
@@ -0,0 +1,356 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mauvalente/go-bid/internal/jsonutils"
+	"github.com/mauvalente/go-bid/internal/services"
+	"github.com/mauvalente/go-bid/internal/usecase/bid"
+)
+
+const (
+	maxIdempotencyKeyLength = 255
+	// bidSubmitTimeout limita a espera pela sala. Com Idempotency-Key a espera
+	// não acompanha a requisição: se o cliente cair ou o prazo estourar, a
+	// resposta ainda é guardada para o retry.
+	bidSubmitTimeout = 10 * time.Second
+)
+
+// handlePlaceBid é o lance pela API REST. Passa pela sala como os lances do
+// websocket, então os demais participantes recebem o mesmo evento.
+func (api *Api) handlePlaceBid(w http.ResponseWriter, r *http.Request) {
+	productId, err := uuid.Parse(chi.URLParam(r, "product_id"))
+	if err != nil {
+		jsonutils.EncodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"message": "invalid product id, must be a valid id",
+		})
+		return
+	}
+
+	data, problems, err := jsonutils.DecodeValidJson[bid.PlaceBidReq](r)
+	if err != nil {
+		jsonutils.EncodeJson(w, r, http.StatusUnprocessableEntity, problems)
+		return
+	}
+
+	userId, ok := api.Sessions.Get(r.Context(), "AuthenticatedUserId").(uuid.UUID)
+	if !ok {
+		jsonutils.EncodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later",
+		})
+		return
+	}
+
+	key := r.Header.Get("Idempotency-Key")
+	if len(key) > maxIdempotencyKeyLength {
+		jsonutils.EncodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"message": fmt.Sprintf("invalid Idempotency-Key, must have at most %d characters", maxIdempotencyKeyLength),
+		})
+		return
+	}
+
+	respond := idempotentResponder{service: &api.IdempotencyService, userId: userId, key: key}
+	if key != "" {
+		stored, err := api.IdempotencyService.Reserve(r.Context(), userId, key, productId, bidRequestHash(data))
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrIdempotencyKeyInProgress):
+				jsonutils.EncodeJson(w, r, http.StatusConflict, map[string]any{
+					"error": err.Error(),
+				})
+			case errors.Is(err, services.ErrIdempotencyKeyMismatch):
+				jsonutils.EncodeJson(w, r, http.StatusUnprocessableEntity, map[string]any{
+					"error": err.Error(),
+				})
+			default:
+				jsonutils.EncodeJson(w, r, http.StatusInternalServerError, map[string]any{
+					"error": "unexpected error, try again later",
+				})
+			}
+			return
+		}
+
+		if stored != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(stored.StatusCode)
+			w.Write(stored.Body)
+			return
+		}
+	}
+
+	product, err := api.ProductService.GetProductById(r.Context(), productId)
+	if err != nil {
+		if errors.Is(err, services.ErrProductNotFound) {
+			respond.json(w, r, http.StatusNotFound, map[string]any{
+				"error": "no product with given id",
+			})
+			return
+		}
+		respond.json(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later",
+		})
+		return
+	}
+
+	room, ok := api.openAuctionRoom(product)
+	if !ok {
+		respond.json(w, r, http.StatusBadRequest, map[string]any{
+			"error":      services.ErrAuctionHasEnded.Error(),
+			"error_code": "auction_ended",
+		})
+		return
+	}
+
+	ctx := r.Context()
+	if key != "" {
+		ctx = context.WithoutCancel(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, bidSubmitTimeout)
+	defer cancel()
+
+	replies, err := room.Send(ctx, services.Message{
+		Kind:      services.PlaceBid,
+		UserId:    userId,
+		Amount:    data.Amount,
+		Quantity:  data.Quantity,
+		RequestId: data.RequestId,
+	})
+	if err != nil {
+		if errors.Is(err, services.ErrAuctionRoomClosed) {
+			// a sala também fecha no shutdown com o leilão ainda aberto: só o
+			// banco diz se ele acabou, e a resposta fica guardada na chave
+			if api.auctionEnded(r.Context(), productId) {
+				respond.json(w, r, http.StatusBadRequest, map[string]any{
+					"error":      services.ErrAuctionHasEnded.Error(),
+					"error_code": "auction_ended",
+				})
+				return
+			}
+			respond.json(w, r, http.StatusServiceUnavailable, map[string]any{
+				"error": "the auction is temporarily unavailable, try again later",
+			})
+			return
+		}
+		// a sala não chegou a receber o lance: a chave fica livre para o retry
+		slog.Error("Bid request timed out", "auctionId", productId, "user_id", userId, "error", err)
+		respond.json(w, r, http.StatusGatewayTimeout, map[string]any{
+			"error": "the auction is busy, try again later",
+		})
+		return
+	}
+
+	select {
+	case reply := <-replies:
+		status, body := bidReplyResponse(reply, productId, userId)
+		respond.json(w, r, status, body)
+	case <-ctx.Done():
+		// a sala recebeu o lance e ainda vai responder: a chave segue travada
+		// até lá, e então guarda o resultado real para o retry
+		slog.Error("Bid request timed out", "auctionId", productId, "user_id", userId, "error", ctx.Err())
+		if key != "" {
+			go respond.await(room, replies, productId)
+		}
+		jsonutils.EncodeJson(w, r, http.StatusGatewayTimeout, map[string]any{
+			"error": "the bid could not be confirmed in time, check the auction state before bidding again",
+		})
+	}
+}
+
+// bidReplyResponse traduz a resposta da sala para a resposta HTTP.
+func bidReplyResponse(reply services.Message, productId, userId uuid.UUID) (int, map[string]any) {
+	if reply.Kind == services.FailedToPlaceBid {
+		status := http.StatusBadRequest
+		if reply.ErrorCode == services.ErrorCodeInternal {
+			status = http.StatusInternalServerError
+		}
+
+		response := map[string]any{
+			"error":      reply.Message,
+			"error_code": reply.ErrorCode,
+		}
+		if reply.Amount > 0 {
+			response["minimum_bid"] = reply.Amount
+		}
+		return status, response
+	}
+
+	return http.StatusCreated, map[string]any{
+		"message": reply.Message,
+		"bid": map[string]any{
+			"id":         reply.BidId,
+			"product_id": productId,
+			"bidder_id":  userId,
+			"amount":     reply.Amount,
+			"quantity":   reply.Quantity,
+		},
+		"request_id": reply.RequestId,
+	}
+}
+
+// bidRequestHash identifica o conteúdo do pedido, para recusar a mesma chave
+// usada com outro lance.
+func bidRequestHash(data bid.PlaceBidReq) string {
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s|%d", data.Amount, data.Quantity))
+	return hex.EncodeToString(sum[:])
+}
+
+// idempotentResponder guarda a resposta de um pedido com Idempotency-Key antes
+// de enviá-la. Falhas internas não tiveram efeito e liberam a chave para o
+// retry.
+type idempotentResponder struct {
+	service *services.IdempotencyService
+	userId  uuid.UUID
+	key     string
+}
+
+func (ir idempotentResponder) json(w http.ResponseWriter, r *http.Request, status int, body map[string]any) {
+	if ir.key != "" {
+		ir.store(context.WithoutCancel(r.Context()), status, body)
+	}
+
+	jsonutils.EncodeJson(w, r, status, body)
+}
+
+func (ir idempotentResponder) store(ctx context.Context, status int, body map[string]any) {
+	var err error
+	if status >= http.StatusInternalServerError {
+		err = ir.service.Release(ctx, ir.userId, ir.key)
+	} else {
+		var data []byte
+		data, err = json.Marshal(body)
+		if err == nil {
+			err = ir.service.Complete(ctx, ir.userId, ir.key, services.StoredResponse{StatusCode: status, Body: data})
+		}
+	}
+	if err != nil {
+		slog.Error("Failed to store idempotent response", "user_id", ir.userId, "key", ir.key, "error", err)
+	}
+}
+
+// await continua esperando a resposta de um lance que estourou o prazo e
+// guarda o resultado real na chave. Se a sala parar antes de responder, a
+// chave fica travada até vencer.
+func (ir idempotentResponder) await(room *services.AuctionRoom, replies <-chan services.Message, productId uuid.UUID) {
+	select {
+	case reply := <-replies:
+		status, body := bidReplyResponse(reply, productId, ir.userId)
+		ir.store(context.Background(), status, body)
+	case <-room.Context.Done():
+		slog.Warn("Auction room stopped before answering a timed out bid", "auctionId", productId, "user_id", ir.userId, "key", ir.key)
+	}
+}
+
+func (api *Api) handleListProductBids(w http.ResponseWriter, r *http.Request) {
+	productId, err := uuid.Parse(chi.URLParam(r, "product_id"))
+	if err != nil {
+		jsonutils.EncodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"message": "invalid product id, must be a valid id",
+		})
+		return
+	}
+
+	cursor, pageSize, problems := parseBidPage(r)
+	if len(problems) > 0 {
+		jsonutils.EncodeJson(w, r, http.StatusUnprocessableEntity, problems)
+		return
+	}
+
+	userId, ok := api.Sessions.Get(r.Context(), "AuthenticatedUserId").(uuid.UUID)
+	if !ok {
+		jsonutils.EncodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later",
+		})
+		return
+	}
+
+	if _, err := api.ProductService.GetProductById(r.Context(), productId); err != nil {
+		if errors.Is(err, services.ErrProductNotFound) {
+			jsonutils.EncodeJson(w, r, http.StatusNotFound, map[string]any{
+				"error": "no product with given id",
+			})
+			return
+		}
+		jsonutils.EncodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later",
+		})
+		return
+	}
+
+	page, err := api.BidService.ProductBids(r.Context(), productId, userId, cursor, pageSize)
+	if err != nil {
+		jsonutils.EncodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later",
+		})
+		return
+	}
+
+	jsonutils.EncodeJson(w, r, http.StatusOK, page)
+}
+
+func (api *Api) handleListMyBids(w http.ResponseWriter, r *http.Request) {
+	cursor, pageSize, problems := parseBidPage(r)
+
+	outcome := services.BidOutcome(r.URL.Query().Get("status"))
+	if outcome != "" && !outcome.Valid() {
+		problems["status"] = "this field must be one of active, won or lost"
+	}
+	if len(problems) > 0 {
+		jsonutils.EncodeJson(w, r, http.StatusUnprocessableEntity, problems)
+		return
+	}
+
+	userId, ok := api.Sessions.Get(r.Context(), "AuthenticatedUserId").(uuid.UUID)
+	if !ok {
+		jsonutils.EncodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later",
+		})
+		return
+	}
+
+	page, err := api.BidService.UserBids(r.Context(), userId, outcome, cursor, pageSize)
+	if err != nil {
+		jsonutils.EncodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later",
+		})
+		return
+	}
+
+	jsonutils.EncodeJson(w, r, http.StatusOK, page)
+}
+
+// parseBidPage lê ?cursor= e ?limit= das listagens de lances.
+func parseBidPage(r *http.Request) (*services.BidCursor, int32, map[string]string) {
+	problems := make(map[string]string)
+
+	var cursor *services.BidCursor
+	if raw := r.URL.Query().Get("cursor"); raw != "" {
+		parsed, err := services.ParseBidCursor(raw)
+		if err != nil {
+			problems["cursor"] = "this field must be a next_cursor returned by a previous page"
+		}
+		cursor = &parsed
+	}
+
+	pageSize := int32(services.DefaultBidPageSize)
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		limit, err := strconv.ParseInt(raw, 10, 32)
+		if err != nil || limit < 1 || limit > services.MaxBidPageSize {
+			problems["limit"] = fmt.Sprintf("this field must be between 1 and %d", services.MaxBidPageSize)
+		}
+		pageSize = int32(limit)
+	}
+
+	return cursor, pageSize, problems
+}
@@ -1,14 +1,16 @@
 package api
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/mauvalente/go-bid/internal/jsonutils"
 	"github.com/mauvalente/go-bid/internal/services"
+	"github.com/mauvalente/go-bid/internal/store/pgstore"
 	"github.com/mauvalente/go-bid/internal/usecase/product"
 )
 
@@ -27,6 +29,11 @@ func (api *Api) HandleListProducts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// o preço de reserva é segredo do vendedor
+	for i := range products {
+		products[i].ReservePrice = nil
+	}
+
 	jsonutils.EncodeJson(w, r, http.StatusOK, products)
 }
 
@@ -45,14 +52,22 @@ func (api *Api) HandleCreateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	productId, err := api.ProductService.CreateProduct(
-		r.Context(),
-		SellerID,
-		data.ProductName,
-		data.Description,
-		data.Baseprice,
-		data.AuctionEnd,
-	)
+	created, err := api.ProductService.CreateProduct(r.Context(), pgstore.CreateProductParams{
+		SellerID:      SellerID,
+		ProductName:   data.ProductName,
+		Description:   data.Description,
+		Baseprice:     data.Baseprice,
+		AuctionStart:  data.AuctionStart,
+		AuctionEnd:    data.AuctionEnd,
+		Currency:      data.Currency,
+		MinIncrement:  data.MinIncrement,
+		ReservePrice:  data.ReservePrice,
+		BuyNowPrice:   data.BuyNowPrice,
+		AuctionType:   data.AuctionType,
+		DutchSchedule: data.DutchSchedule,
+		Quantity:      data.Quantity,
+		PricingRule:   data.PricingRule,
+	})
 	if err != nil {
 		fmt.Println(err)
 		jsonutils.EncodeJson(w, r, http.StatusBadRequest, map[string]any{
@@ -61,21 +76,81 @@ func (api *Api) HandleCreateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx, cancel := context.WithDeadline(context.Background(), data.AuctionEnd)
+	api.startAuctionRoom(created)
 
-	_ = cancel
+	message := "Auction has started with success"
+	if data.AuctionStart.After(time.Now()) {
+		message = "Auction has been scheduled with success"
+	}
 
-	auctionRoom := services.NewAuctionRoom(ctx, productId, api.BidService)
+	jsonutils.EncodeJson(w, r, http.StatusCreated, map[string]any{
+		"message":    message,
+		"product_id": created.ID,
+	})
 
-	go auctionRoom.Run()
+}
 
-	api.AuctionLobby.Lock()
-	api.AuctionLobby.Rooms[productId] = auctionRoom
-	api.AuctionLobby.Unlock()
+// handleGetProduct é público para que os anúncios possam ser compartilhados;
+// com sessão, o resumo traz também a posição de quem está vendo.
+func (api *Api) handleGetProduct(w http.ResponseWriter, r *http.Request) {
+	productId, err := uuid.Parse(chi.URLParam(r, "product_id"))
+	if err != nil {
+		jsonutils.EncodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"message": "invalid product id, must be a valid id",
+		})
+		return
+	}
 
-	jsonutils.EncodeJson(w, r, http.StatusCreated, map[string]any{
-		"message":    "Auction has started with success",
-		"product_id": productId,
+	product, err := api.ProductService.GetProductById(r.Context(), productId)
+	if err != nil {
+		if errors.Is(err, services.ErrProductNotFound) {
+			jsonutils.EncodeJson(w, r, http.StatusNotFound, map[string]any{
+				"error": "no product with given id",
+			})
+			return
+		}
+		jsonutils.EncodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later",
+		})
+		return
+	}
+
+	seller, err := api.UserService.GetPublicProfile(r.Context(), product.SellerID)
+	if err != nil {
+		jsonutils.EncodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later",
+		})
+		return
+	}
+
+	// visitante sem login vê o leilão sem posição
+	viewerId, _ := api.Sessions.Get(r.Context(), "AuthenticatedUserId").(uuid.UUID)
+
+	summary, err := api.BidService.AuctionSummary(r.Context(), product, viewerId, time.Now())
+	if err != nil {
+		jsonutils.EncodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later",
+		})
+		return
+	}
+
+	// o preço de reserva é segredo do vendedor
+	product.ReservePrice = nil
+
+	jsonutils.EncodeJson(w, r, http.StatusOK, map[string]any{
+		"product":       product,
+		"seller":        seller,
+		"auction":       summary,
+		"websocket_url": websocketURL(r, productId),
 	})
+}
 
+// websocketURL monta o endereço da sala a partir do host da requisição,
+// respeitando o TLS terminado no proxy.
+func websocketURL(r *http.Request, productId uuid.UUID) string {
+	scheme := "ws"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "wss"
+	}
+	return fmt.Sprintf("%s://%s/api/v1/products/ws/subscribe/%s", scheme, r.Host, productId)
 }
@@ -0,0 +1,13 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/mauvalente/go-bid/internal/services"
+)
+
+func (api *Api) handleProtocolSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(services.ProtocolV1Schema)
+}
@@ -1,6 +1,9 @@
 package api
 
 import (
+	"expvar"
+	"net/http"
+
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
@@ -27,6 +30,7 @@ func (api *Api) BindRoutes() {
 
 		r.Route("/v1", func(r chi.Router) {
 			// r.Get("/csrftoken", api.HandlerGetCSRFToken)
+			r.Get("/protocol/gobid.v1.schema.json", api.handleProtocolSchema)
 			r.Route("/users", func(r chi.Router) {
 				r.Post("/login", api.handleLoginUser)
 				r.Post("/signup", api.handleSignupUser)
@@ -38,14 +42,34 @@ func (api *Api) BindRoutes() {
 			})
 
 			r.Route("/products", func(r chi.Router) {
+				r.Get("/{product_id}", api.handleGetProduct)
+
 				r.Group(func(r chi.Router) {
 					r.Use(api.AuthMiddleware)
 					r.Post("/", api.HandleCreateProduct)
 					r.Get("/", api.HandleListProducts)
 
+					r.Post("/{product_id}/buy-now", api.handleBuyNow)
+					r.Post("/{product_id}/bids", api.handlePlaceBid)
+					r.Get("/{product_id}/bids", api.handleListProductBids)
+					r.Get("/{product_id}/events", api.handleAuctionEvents)
+
 					r.Get("/ws/subscribe/{product_id}", api.handleSubscribeUserToAuction)
 				})
 			})
+
+			r.Route("/me", func(r chi.Router) {
+				r.Use(api.AuthMiddleware)
+				r.Get("/bids", api.handleListMyBids)
+			})
 		})
 	})
 }
+
+// DebugRouter expõe as métricas fora do router público: deve ser servido
+// apenas num listener interno.
+func (api *Api) DebugRouter() http.Handler {
+	r := chi.NewMux()
+	r.Handle("/debug/vars", expvar.Handler())
+	return r
+}
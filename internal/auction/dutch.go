@@ -0,0 +1,57 @@
+package auction
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mauvalente/go-bid/internal/money"
+)
+
+// DutchSchedule descreve a queda de preço de um leilão holandês. O preço
+// começa no preço base do produto e cai Step a cada IntervalSeconds até
+// chegar em FloorPrice.
+type DutchSchedule struct {
+	FloorPrice      money.Amount `json:"floor_price"`
+	Step            money.Amount `json:"step"`
+	IntervalSeconds int64        `json:"interval_seconds"`
+}
+
+func (s DutchSchedule) Validate(startPrice money.Amount) error {
+	if s.FloorPrice <= 0 || s.FloorPrice >= startPrice {
+		return errors.New("the floor price must be greater than 0 and lower than the start price")
+	}
+	if s.Step <= 0 {
+		return errors.New("the step must be greater than 0")
+	}
+	if s.IntervalSeconds <= 0 {
+		return errors.New("the interval must be at least one second")
+	}
+	return nil
+}
+
+func (s DutchSchedule) Interval() time.Duration {
+	return time.Duration(s.IntervalSeconds) * time.Second
+}
+
+// PriceAt calcula o preço no instante t. Como depende só do relógio, qualquer
+// instância chega ao mesmo valor sem precisar guardar estado.
+func (s DutchSchedule) PriceAt(startPrice money.Amount, start, t time.Time) money.Amount {
+	if !t.After(start) {
+		return startPrice
+	}
+	ticks := int64(t.Sub(start) / s.Interval())
+	return max(startPrice-money.Amount(ticks)*s.Step, s.FloorPrice)
+}
+
+// NextTick devolve o instante da próxima queda depois de t, ou false quando o
+// preço já chegou ao piso.
+func (s DutchSchedule) NextTick(startPrice money.Amount, start, t time.Time) (time.Time, bool) {
+	if s.PriceAt(startPrice, start, t) <= s.FloorPrice {
+		return time.Time{}, false
+	}
+	if t.Before(start) {
+		return start.Add(s.Interval()), true
+	}
+	ticks := int64(t.Sub(start)/s.Interval()) + 1
+	return start.Add(time.Duration(ticks) * s.Interval()), true
+}
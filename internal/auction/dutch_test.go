@@ -0,0 +1,61 @@
+package auction
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mauvalente/go-bid/internal/money"
+)
+
+func TestDutchSchedule(t *testing.T) {
+	const startPrice money.Amount = 10_000
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	schedule := DutchSchedule{FloorPrice: 7_000, Step: 1_000, IntervalSeconds: 60}
+
+	tests := []struct {
+		name     string
+		at       time.Duration
+		price    money.Amount
+		next     time.Duration
+		wantNext bool
+	}{
+		{name: "before the start", at: -time.Minute, price: 10_000, next: time.Minute, wantNext: true},
+		{name: "at the start", at: 0, price: 10_000, next: time.Minute, wantNext: true},
+		{name: "inside the first interval", at: 59 * time.Second, price: 10_000, next: time.Minute, wantNext: true},
+		{name: "first tick", at: time.Minute, price: 9_000, next: 2 * time.Minute, wantNext: true},
+		{name: "between ticks", at: 150 * time.Second, price: 8_000, next: 3 * time.Minute, wantNext: true},
+		{name: "reaches the floor", at: 3 * time.Minute, price: 7_000},
+		{name: "stays at the floor", at: time.Hour, price: 7_000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := start.Add(tt.at)
+
+			if got := schedule.PriceAt(startPrice, start, at); got != tt.price {
+				t.Fatalf("PriceAt = %s, want %s", got, tt.price)
+			}
+
+			next, ok := schedule.NextTick(startPrice, start, at)
+			if ok != tt.wantNext {
+				t.Fatalf("NextTick ok = %v, want %v", ok, tt.wantNext)
+			}
+			if ok && !next.Equal(start.Add(tt.next)) {
+				t.Fatalf("NextTick = %s, want %s", next, start.Add(tt.next))
+			}
+		})
+	}
+}
+
+func TestDutchScheduleStepPastTheFloor(t *testing.T) {
+	// um passo que atravessa o piso para no piso, sem ficar abaixo dele
+	schedule := DutchSchedule{FloorPrice: 2_500, Step: 4_000, IntervalSeconds: 10}
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if got := schedule.PriceAt(10_000, start, start.Add(20*time.Second)); got != 2_500 {
+		t.Fatalf("PriceAt = %s, want the floor 25.00", got)
+	}
+	if _, ok := schedule.NextTick(10_000, start, start.Add(20*time.Second)); ok {
+		t.Fatal("NextTick returned a tick after the floor")
+	}
+}
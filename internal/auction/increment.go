@@ -0,0 +1,104 @@
+package auction
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/mauvalente/go-bid/internal/money"
+)
+
+type IncrementKind string
+
+const (
+	IncrementFixed   IncrementKind = "fixed"
+	IncrementPercent IncrementKind = "percent"
+	IncrementTiered  IncrementKind = "tiered"
+)
+
+// IncrementTier vale para preços a partir de From (inclusive).
+type IncrementTier struct {
+	From   money.Amount `json:"from"`
+	Amount money.Amount `json:"amount"`
+}
+
+// IncrementRule define o quanto um novo lance precisa superar o preço atual.
+type IncrementRule struct {
+	Kind    IncrementKind   `json:"kind"`
+	Amount  money.Amount    `json:"amount,omitempty"`
+	Percent int64           `json:"percent,omitempty"`
+	Tiers   []IncrementTier `json:"tiers,omitempty"`
+}
+
+// DefaultIncrementRule mantém o comportamento antigo: qualquer valor acima do
+// preço atual é aceito.
+var DefaultIncrementRule = IncrementRule{Kind: IncrementFixed, Amount: 1}
+
+func (r IncrementRule) Validate() error {
+	switch r.Kind {
+	case IncrementFixed:
+		if r.Amount <= 0 {
+			return errors.New("fixed increments must have an amount greater than 0")
+		}
+	case IncrementPercent:
+		if r.Percent <= 0 || r.Percent > 100 {
+			return errors.New("percent increments must be between 1 and 100")
+		}
+	case IncrementTiered:
+		if len(r.Tiers) == 0 {
+			return errors.New("tiered increments must have at least one tier")
+		}
+		seen := make(map[money.Amount]bool, len(r.Tiers))
+		for _, tier := range r.Tiers {
+			if tier.Amount <= 0 || tier.From < 0 {
+				return errors.New("every tier must have a positive amount and a non negative start")
+			}
+			if seen[tier.From] {
+				return fmt.Errorf("duplicated tier starting at %s", tier.From)
+			}
+			seen[tier.From] = true
+		}
+		// sem uma faixa a partir de zero os preços abaixo da primeira ficariam
+		// sem incremento
+		if !seen[0] {
+			return errors.New("tiered increments must have a tier starting at 0")
+		}
+	default:
+		return fmt.Errorf("unknown increment kind %q", r.Kind)
+	}
+	return nil
+}
+
+// Increment devolve o incremento mínimo sobre o preço atual.
+func (r IncrementRule) Increment(current money.Amount) money.Amount {
+	var inc money.Amount
+
+	switch r.Kind {
+	case IncrementFixed:
+		inc = r.Amount
+	case IncrementPercent:
+		// arredonda para cima para nunca aceitar menos que o percentual
+		inc = money.Amount((int64(current)*r.Percent + 99) / 100)
+	case IncrementTiered:
+		tiers := make([]IncrementTier, len(r.Tiers))
+		copy(tiers, r.Tiers)
+		sort.Slice(tiers, func(i, j int) bool { return tiers[i].From < tiers[j].From })
+
+		for _, tier := range tiers {
+			if tier.From > current {
+				break
+			}
+			inc = tier.Amount
+		}
+	}
+
+	if inc < 1 {
+		inc = 1
+	}
+	return inc
+}
+
+// MinimumBid é o menor lance aceito dado o preço atual.
+func (r IncrementRule) MinimumBid(current money.Amount) money.Amount {
+	return current + r.Increment(current)
+}
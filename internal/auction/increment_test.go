@@ -0,0 +1,94 @@
+package auction
+
+import (
+	"testing"
+
+	"github.com/mauvalente/go-bid/internal/money"
+)
+
+func TestIncrementRuleValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    IncrementRule
+		wantErr bool
+	}{
+		{name: "fixed", rule: IncrementRule{Kind: IncrementFixed, Amount: 100}},
+		{name: "fixed without amount", rule: IncrementRule{Kind: IncrementFixed}, wantErr: true},
+		{name: "percent", rule: IncrementRule{Kind: IncrementPercent, Percent: 5}},
+		{name: "percent above 100", rule: IncrementRule{Kind: IncrementPercent, Percent: 101}, wantErr: true},
+		{name: "percent zero", rule: IncrementRule{Kind: IncrementPercent}, wantErr: true},
+		{name: "tiered", rule: IncrementRule{Kind: IncrementTiered, Tiers: []IncrementTier{
+			{From: 10_000, Amount: 500},
+			{From: 0, Amount: 100},
+		}}},
+		{name: "tiered without tiers", rule: IncrementRule{Kind: IncrementTiered}, wantErr: true},
+		{name: "tiered without a tier at zero", rule: IncrementRule{Kind: IncrementTiered, Tiers: []IncrementTier{
+			{From: 100, Amount: 50},
+			{From: 10_000, Amount: 500},
+		}}, wantErr: true},
+		{name: "tiered with a duplicated start", rule: IncrementRule{Kind: IncrementTiered, Tiers: []IncrementTier{
+			{From: 0, Amount: 50},
+			{From: 0, Amount: 100},
+		}}, wantErr: true},
+		{name: "tiered with a negative start", rule: IncrementRule{Kind: IncrementTiered, Tiers: []IncrementTier{
+			{From: 0, Amount: 50},
+			{From: -1, Amount: 100},
+		}}, wantErr: true},
+		{name: "tiered with a zero amount", rule: IncrementRule{Kind: IncrementTiered, Tiers: []IncrementTier{
+			{From: 0, Amount: 0},
+		}}, wantErr: true},
+		{name: "unknown kind", rule: IncrementRule{Kind: "linear"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rule.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIncrementRuleMinimumBid(t *testing.T) {
+	tiered := IncrementRule{Kind: IncrementTiered, Tiers: []IncrementTier{
+		{From: 10_000, Amount: 500},
+		{From: 0, Amount: 100},
+		{From: 100_000, Amount: 2_500},
+	}}
+
+	tests := []struct {
+		name    string
+		rule    IncrementRule
+		current money.Amount
+		want    money.Amount
+	}{
+		{name: "default", rule: DefaultIncrementRule, current: 1_000, want: 1_001},
+		{name: "fixed", rule: IncrementRule{Kind: IncrementFixed, Amount: 250}, current: 1_000, want: 1_250},
+		{name: "percent", rule: IncrementRule{Kind: IncrementPercent, Percent: 5}, current: 1_000, want: 1_050},
+		// 5% de 1001 é 50,05 centavos: arredonda para cima
+		{name: "percent rounds up", rule: IncrementRule{Kind: IncrementPercent, Percent: 5}, current: 1_001, want: 1_052},
+		// com preço zero o incremento nunca fica abaixo de um centavo
+		{name: "percent at zero", rule: IncrementRule{Kind: IncrementPercent, Percent: 5}, current: 0, want: 1},
+		{name: "first tier", rule: tiered, current: 0, want: 100},
+		{name: "below the second tier", rule: tiered, current: 9_999, want: 10_099},
+		{name: "second tier start is inclusive", rule: tiered, current: 10_000, want: 10_500},
+		{name: "last tier", rule: tiered, current: 250_000, want: 252_500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.MinimumBid(tt.current); got != tt.want {
+				t.Fatalf("MinimumBid(%s) = %s, want %s", tt.current, got, tt.want)
+			}
+			if got := tt.rule.Increment(tt.current); got != tt.want-tt.current {
+				t.Fatalf("Increment(%s) = %s, want %s", tt.current, got, tt.want-tt.current)
+			}
+		})
+	}
+
+	// a ordenação das faixas não pode alterar a regra guardada
+	if tiered.Tiers[0].From != 10_000 {
+		t.Fatal("Increment reordered the rule tiers")
+	}
+}
@@ -0,0 +1,85 @@
+package auction
+
+import (
+	"github.com/google/uuid"
+	"github.com/mauvalente/go-bid/internal/money"
+)
+
+type PricingRule string
+
+const (
+	// PricingUniform: todos os vencedores pagam o preço de corte.
+	PricingUniform PricingRule = "uniform"
+	// PricingPayAsBid: cada vencedor paga o próprio lance.
+	PricingPayAsBid PricingRule = "pay_as_bid"
+)
+
+func (p PricingRule) Valid() bool {
+	return p == PricingUniform || p == PricingPayAsBid
+}
+
+// UnitBid é o lance vigente de um participante: preço por unidade e
+// quantidade pedida.
+type UnitBid struct {
+	BidId    uuid.UUID
+	BidderId uuid.UUID
+	Amount   money.Amount
+	Quantity int32
+}
+
+// Award é quanto de um lance foi atendido e o preço unitário a pagar. Won
+// zero quer dizer que o lance está superado.
+type Award struct {
+	UnitBid
+	Won   int32
+	Price money.Amount
+}
+
+type Allocation struct {
+	Awards []Award
+	// ClearingPrice é o menor lance atendido, ou zero sem vencedores.
+	ClearingPrice money.Amount
+	// Filled indica que todas as unidades têm dono; a partir daí um novo
+	// lance precisa superar o preço de corte.
+	Filled bool
+}
+
+// Allocate distribui as unidades entre os lances, que devem vir em ordem de
+// prioridade (maior preço primeiro e, no empate, o mais antigo). O último
+// vencedor pode levar só parte do que pediu.
+func Allocate(bids []UnitBid, units int32, rule PricingRule) Allocation {
+	allocation := Allocation{Awards: make([]Award, 0, len(bids))}
+
+	remaining := units
+	for _, bid := range bids {
+		award := Award{UnitBid: bid, Won: min(bid.Quantity, remaining)}
+		if award.Won > 0 {
+			remaining -= award.Won
+			allocation.ClearingPrice = bid.Amount
+		}
+		allocation.Awards = append(allocation.Awards, award)
+	}
+	allocation.Filled = remaining == 0
+
+	for i := range allocation.Awards {
+		award := &allocation.Awards[i]
+		if award.Won == 0 {
+			continue
+		}
+		award.Price = award.Amount
+		if rule == PricingUniform {
+			award.Price = allocation.ClearingPrice
+		}
+	}
+
+	return allocation
+}
+
+// MinimumBid é o menor preço unitário que ainda disputa unidades: o preço
+// base enquanto sobram unidades, depois o preço de corte mais o incremento.
+func (a Allocation) MinimumBid(baseprice money.Amount, rule IncrementRule) money.Amount {
+	if !a.Filled {
+		return baseprice
+	}
+	return rule.MinimumBid(a.ClearingPrice)
+}
@@ -0,0 +1,108 @@
+package auction
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mauvalente/go-bid/internal/money"
+)
+
+func TestAllocate(t *testing.T) {
+	bid := func(amount money.Amount, quantity int32) UnitBid {
+		return UnitBid{BidId: uuid.New(), BidderId: uuid.New(), Amount: amount, Quantity: quantity}
+	}
+
+	tests := []struct {
+		name     string
+		bids     []UnitBid
+		units    int32
+		rule     PricingRule
+		won      []int32
+		prices   []money.Amount
+		clearing money.Amount
+		filled   bool
+	}{
+		{
+			name:  "no bids",
+			units: 3,
+			rule:  PricingUniform,
+		},
+		{
+			name:     "units left over",
+			bids:     []UnitBid{bid(500, 1), bid(400, 1)},
+			units:    3,
+			rule:     PricingUniform,
+			won:      []int32{1, 1},
+			prices:   []money.Amount{400, 400},
+			clearing: 400,
+		},
+		{
+			name:     "uniform with a partial last winner",
+			bids:     []UnitBid{bid(500, 2), bid(400, 2), bid(300, 1)},
+			units:    3,
+			rule:     PricingUniform,
+			won:      []int32{2, 1, 0},
+			prices:   []money.Amount{400, 400, 0},
+			clearing: 400,
+			filled:   true,
+		},
+		{
+			name:     "pay as bid",
+			bids:     []UnitBid{bid(500, 2), bid(400, 2), bid(300, 1)},
+			units:    3,
+			rule:     PricingPayAsBid,
+			won:      []int32{2, 1, 0},
+			prices:   []money.Amount{500, 400, 0},
+			clearing: 400,
+			filled:   true,
+		},
+		{
+			name:     "exactly filled",
+			bids:     []UnitBid{bid(500, 1), bid(400, 2)},
+			units:    3,
+			rule:     PricingUniform,
+			won:      []int32{1, 2},
+			prices:   []money.Amount{400, 400},
+			clearing: 400,
+			filled:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allocation := Allocate(tt.bids, tt.units, tt.rule)
+
+			if len(allocation.Awards) != len(tt.bids) {
+				t.Fatalf("got %d awards for %d bids", len(allocation.Awards), len(tt.bids))
+			}
+			for i, award := range allocation.Awards {
+				if award.BidId != tt.bids[i].BidId {
+					t.Fatalf("award %d is for another bid", i)
+				}
+				if award.Won != tt.won[i] || award.Price != tt.prices[i] {
+					t.Fatalf("award %d: won %d at %s, want %d at %s", i, award.Won, award.Price, tt.won[i], tt.prices[i])
+				}
+			}
+			if allocation.ClearingPrice != tt.clearing {
+				t.Fatalf("clearing price = %s, want %s", allocation.ClearingPrice, tt.clearing)
+			}
+			if allocation.Filled != tt.filled {
+				t.Fatalf("filled = %v, want %v", allocation.Filled, tt.filled)
+			}
+		})
+	}
+}
+
+func TestAllocationMinimumBid(t *testing.T) {
+	rule := IncrementRule{Kind: IncrementFixed, Amount: 50}
+
+	open := Allocation{ClearingPrice: 400}
+	if got := open.MinimumBid(100, rule); got != 100 {
+		t.Fatalf("with units left the minimum is the base price, got %s", got)
+	}
+
+	filled := Allocation{ClearingPrice: 400, Filled: true}
+	if got := filled.MinimumBid(100, rule); got != 450 {
+		t.Fatalf("once filled the minimum is the clearing price plus the increment, got %s", got)
+	}
+}
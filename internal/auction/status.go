@@ -0,0 +1,34 @@
+package auction
+
+import "time"
+
+type Status string
+
+const (
+	StatusUpcoming Status = "upcoming"
+	StatusLive     Status = "live"
+	StatusEnded    Status = "ended"
+	StatusSold     Status = "sold"
+)
+
+// InitialStatus é o status de um leilão recém-criado que começa em start.
+func InitialStatus(start, now time.Time) Status {
+	if start.After(now) {
+		return StatusUpcoming
+	}
+	return StatusLive
+}
+
+// CurrentStatus corrige o status gravado pelo relógio: a coluna só muda na
+// próxima rodada do scheduler ou na liquidação.
+func CurrentStatus(stored Status, start, end, now time.Time) Status {
+	switch {
+	case stored == StatusSold || stored == StatusEnded:
+		return stored
+	case now.Before(start):
+		return StatusUpcoming
+	case now.Before(end):
+		return StatusLive
+	}
+	return StatusEnded
+}
@@ -0,0 +1,24 @@
+package auction
+
+type Type string
+
+const (
+	TypeEnglish          Type = "english"
+	TypeDutch            Type = "dutch"
+	TypeSealedFirstPrice Type = "sealed_first_price"
+	TypeVickrey          Type = "vickrey"
+)
+
+func (t Type) Valid() bool {
+	switch t {
+	case TypeEnglish, TypeDutch, TypeSealedFirstPrice, TypeVickrey:
+		return true
+	}
+	return false
+}
+
+// Sealed indica os leilões de envelope fechado, em que os lances só são
+// revelados no encerramento.
+func (t Type) Sealed() bool {
+	return t == TypeSealedFirstPrice || t == TypeVickrey
+}
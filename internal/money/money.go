@@ -0,0 +1,144 @@
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Amount é um valor monetário em unidades menores da moeda (centavos).
+// Trabalhar com inteiros evita os erros de arredondamento do float64 na
+// comparação de lances.
+type Amount int64
+
+const minorUnits = 100
+
+var ErrInvalidAmount = errors.New("invalid monetary amount")
+
+func (a Amount) String() string {
+	sign := ""
+	v := int64(a)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/minorUnits, v%minorUnits)
+}
+
+// Parse lê valores como "10", "10.5" ou "10.50". Mais de duas casas decimais
+// são rejeitadas em vez de arredondadas.
+func Parse(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, ErrInvalidAmount
+	}
+
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	units, cents, hasCents := strings.Cut(s, ".")
+	if units == "" || (hasCents && (cents == "" || len(cents) > 2)) {
+		return 0, ErrInvalidAmount
+	}
+	// o ParseInt aceitaria sinais ("1.-5", "-+5")
+	if !digitsOnly(units) || !digitsOnly(cents) {
+		return 0, ErrInvalidAmount
+	}
+
+	u, err := strconv.ParseInt(units, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidAmount
+	}
+
+	var c int64
+	if hasCents {
+		if len(cents) == 1 {
+			cents += "0"
+		}
+		c, err = strconv.ParseInt(cents, 10, 64)
+		if err != nil {
+			return 0, ErrInvalidAmount
+		}
+	}
+
+	v := u*minorUnits + c
+	if v/minorUnits != u {
+		return 0, ErrInvalidAmount
+	}
+	if negative {
+		v = -v
+	}
+	return Amount(v), nil
+}
+
+func digitsOnly(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// MarshalJSON escreve o valor como texto ("10.50"), nunca como número JSON.
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// UnmarshalJSON aceita tanto "10.50" quanto 10.50, lendo o texto do número
+// diretamente para não passar por float64.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	raw := string(data)
+	if strings.HasPrefix(raw, `"`) {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return err
+		}
+	}
+
+	v, err := Parse(raw)
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, string(data))
+	}
+	*a = v
+	return nil
+}
+
+func (a Amount) Int64Value() (pgtype.Int8, error) {
+	return pgtype.Int8{Int64: int64(a), Valid: true}, nil
+}
+
+func (a *Amount) ScanInt64(v pgtype.Int8) error {
+	if !v.Valid {
+		return fmt.Errorf("cannot scan NULL into %T", a)
+	}
+	*a = Amount(v.Int64)
+	return nil
+}
+
+type Currency string
+
+const (
+	BRL Currency = "BRL"
+	USD Currency = "USD"
+	EUR Currency = "EUR"
+
+	DefaultCurrency = BRL
+)
+
+func (c Currency) Valid() bool {
+	switch c {
+	case BRL, USD, EUR:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Amount
+		wantErr bool
+	}{
+		{in: "10", want: 1000},
+		{in: "10.5", want: 1050},
+		{in: "10.50", want: 1050},
+		{in: "0.01", want: 1},
+		{in: " 7.25 ", want: 725},
+		{in: "-1.5", want: -150},
+		{in: "-0.05", want: -5},
+		{in: "92233720368547758.07", want: math.MaxInt64},
+
+		// sinais só na frente, e só o "-"
+		{in: "+5", wantErr: true},
+		{in: "-+5", wantErr: true},
+		{in: "--5", wantErr: true},
+		{in: "1.-5", wantErr: true},
+		{in: "1.+5", wantErr: true},
+		{in: "1.5-", wantErr: true},
+
+		// casas decimais
+		{in: "1.005", wantErr: true},
+		{in: "1.", wantErr: true},
+		{in: ".5", wantErr: true},
+		{in: "1.2.3", wantErr: true},
+
+		// notação que o ParseFloat aceitaria
+		{in: "1e3", wantErr: true},
+		{in: "0x10", wantErr: true},
+		{in: "1_000", wantErr: true},
+
+		// estouro perto do MaxInt64
+		{in: "92233720368547758.08", wantErr: true},
+		{in: "92233720368547759", wantErr: true},
+		{in: "9223372036854775808", wantErr: true},
+		{in: "-92233720368547758.09", wantErr: true},
+
+		{in: "", wantErr: true},
+		{in: "   ", wantErr: true},
+		{in: "abc", wantErr: true},
+		{in: "-", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidAmount) {
+				t.Errorf("Parse(%q) = %d, %v; want ErrInvalidAmount", tt.in, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Parse(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestAmountString(t *testing.T) {
+	tests := []struct {
+		in   Amount
+		want string
+	}{
+		{in: 0, want: "0.00"},
+		{in: 5, want: "0.05"},
+		{in: 1050, want: "10.50"},
+		{in: -150, want: "-1.50"},
+		{in: math.MaxInt64, want: "92233720368547758.07"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("Amount(%d).String() = %q, want %q", int64(tt.in), got, tt.want)
+		}
+	}
+}
+
+func TestAmountJSON(t *testing.T) {
+	unmarshal := []struct {
+		in      string
+		want    Amount
+		wantErr bool
+	}{
+		{in: `10.5`, want: 1050},
+		{in: `10`, want: 1000},
+		{in: `"10.50"`, want: 1050},
+		{in: `"7"`, want: 700},
+		{in: `-0.05`, want: -5},
+		{in: `1e3`, wantErr: true},
+		{in: `"1e3"`, wantErr: true},
+		{in: `10.005`, wantErr: true},
+		{in: `"abc"`, wantErr: true},
+		{in: `"1.-5"`, wantErr: true},
+		{in: `true`, wantErr: true},
+	}
+
+	for _, tt := range unmarshal {
+		var got Amount
+		err := json.Unmarshal([]byte(tt.in), &got)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("unmarshal %s = %d, want an error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("unmarshal %s = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+
+	// null não mexe no valor
+	got := Amount(42)
+	if err := json.Unmarshal([]byte(`null`), &got); err != nil || got != 42 {
+		t.Errorf("unmarshal null = %d, %v; want 42 untouched", got, err)
+	}
+
+	// a saída é sempre string, e volta ao mesmo valor
+	for _, amount := range []Amount{0, 1, 1050, -150, math.MaxInt64} {
+		data, err := json.Marshal(amount)
+		if err != nil {
+			t.Fatalf("marshal %d: %v", int64(amount), err)
+		}
+		if want := `"` + amount.String() + `"`; string(data) != want {
+			t.Errorf("marshal %d = %s, want %s", int64(amount), data, want)
+		}
+
+		var back Amount
+		if err := json.Unmarshal(data, &back); err != nil || back != amount {
+			t.Errorf("round trip %d = %d, %v", int64(amount), back, err)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/mauvalente/go-bid/internal/auction"
+	"github.com/mauvalente/go-bid/internal/money"
+	"github.com/mauvalente/go-bid/internal/store/pgstore"
+)
+
+type PositionStatus string
+
+const (
+	PositionNone    PositionStatus = "none"
+	PositionWinning PositionStatus = "winning"
+	PositionOutbid  PositionStatus = "outbid"
+	// PositionSealed: o usuário tem um lance fechado, que só será comparado
+	// no encerramento.
+	PositionSealed PositionStatus = "sealed"
+)
+
+// BidderPosition é a situação de quem pediu o estado. Só ele recebe o próprio
+// lance máximo.
+type BidderPosition struct {
+	Status   PositionStatus `json:"status"`
+	Amount   money.Amount   `json:"amount,omitempty"`
+	MaxBid   *money.Amount  `json:"max_bid,omitempty"`
+	Quantity int32          `json:"quantity,omitempty"`
+}
+
+// AuctionSnapshot é a foto do leilão enviada a quem acabou de entrar na sala.
+// Em envelope fechado o maior lance não aparece.
+type AuctionSnapshot struct {
+	AuctionType  auction.Type   `json:"auction_type"`
+	Status       string         `json:"status"`
+	AuctionStart time.Time      `json:"auction_start"`
+	AuctionEnd   time.Time      `json:"auction_end"`
+	Quantity     int32          `json:"quantity"`
+	CurrentPrice money.Amount   `json:"current_price,omitempty"`
+	HighestBid   *money.Amount  `json:"highest_bid,omitempty"`
+	MinimumBid   money.Amount   `json:"minimum_bid,omitempty"`
+	Bidders      int64          `json:"bidders"`
+	ReserveMet   *bool          `json:"reserve_met,omitempty"`
+	Position     BidderPosition `json:"position"`
+}
+
+// AuctionState monta a foto do leilão a partir do banco, então ela continua
+// correta depois de um restart ou quando os lances vieram de outra instância.
+func (bs *BidService) AuctionState(ctx context.Context, product_id, user_id uuid.UUID) (AuctionSnapshot, error) {
+	product, err := bs.queries.GetProductById(ctx, product_id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return AuctionSnapshot{}, ErrProductNotFound
+		}
+		return AuctionSnapshot{}, err
+	}
+
+	return bs.auctionState(ctx, product, user_id)
+}
+
+func (bs *BidService) auctionState(ctx context.Context, product pgstore.Product, user_id uuid.UUID) (AuctionSnapshot, error) {
+	var err error
+	snapshot := AuctionSnapshot{
+		AuctionType:  product.AuctionType,
+		Status:       product.Status,
+		AuctionStart: product.AuctionStart,
+		AuctionEnd:   product.AuctionEnd,
+		Quantity:     product.Quantity,
+		Position:     BidderPosition{Status: PositionNone},
+	}
+
+	switch {
+	case product.AuctionType.Sealed():
+		err = bs.sealedState(ctx, product, user_id, &snapshot)
+	case product.AuctionType == auction.TypeDutch:
+		snapshot.CurrentPrice = DutchPrice(product, time.Now())
+	case product.Quantity > 1:
+		err = bs.multiUnitState(ctx, product, user_id, &snapshot)
+	default:
+		err = bs.englishState(ctx, product, user_id, &snapshot)
+	}
+	if err != nil {
+		return AuctionSnapshot{}, err
+	}
+
+	return snapshot, nil
+}
+
+// AuctionSummary é o resumo do leilão na página pública do produto. O status
+// já vem corrigido pelo relógio.
+type AuctionSummary struct {
+	AuctionSnapshot
+	BidCount             int64 `json:"bid_count"`
+	TimeRemainingSeconds int64 `json:"time_remaining_seconds"`
+	StartsInSeconds      int64 `json:"starts_in_seconds,omitempty"`
+}
+
+// AuctionSummary aceita viewer_id nulo para visitantes sem login; nesse caso a
+// posição vem como "none".
+func (bs *BidService) AuctionSummary(ctx context.Context, product pgstore.Product, viewer_id uuid.UUID, now time.Time) (AuctionSummary, error) {
+	snapshot, err := bs.auctionState(ctx, product, viewer_id)
+	if err != nil {
+		return AuctionSummary{}, err
+	}
+
+	status := auction.CurrentStatus(auction.Status(product.Status), product.AuctionStart, product.AuctionEnd, now)
+	summary := AuctionSummary{AuctionSnapshot: snapshot}
+	summary.Status = string(status)
+
+	// em envelope fechado os lances só chegam à tabela de lances na liquidação
+	if product.AuctionType.Sealed() && !product.SettledAt.Valid {
+		summary.BidCount, err = bs.queries.CountSealedBidsByProductId(ctx, product.ID)
+	} else {
+		summary.BidCount, err = bs.queries.CountBidsByProductId(ctx, product.ID)
+	}
+	if err != nil {
+		return AuctionSummary{}, err
+	}
+
+	switch status {
+	case auction.StatusUpcoming:
+		summary.StartsInSeconds = int64(product.AuctionStart.Sub(now).Seconds())
+		summary.TimeRemainingSeconds = int64(product.AuctionEnd.Sub(now).Seconds())
+	case auction.StatusLive:
+		summary.TimeRemainingSeconds = int64(product.AuctionEnd.Sub(now).Seconds())
+	}
+
+	return summary, nil
+}
+
+func (bs *BidService) englishState(ctx context.Context, product pgstore.Product, user_id uuid.UUID, snapshot *AuctionSnapshot) error {
+	currentPrice, leaderId, err := bs.currentPrice(ctx, bs.queries, product)
+	if err != nil {
+		return err
+	}
+
+	snapshot.CurrentPrice = currentPrice
+	snapshot.MinimumBid = product.MinIncrement.MinimumBid(currentPrice)
+	if leaderId != uuid.Nil {
+		snapshot.HighestBid = &currentPrice
+
+		// mesma regra da liquidação: conta o máximo do líder
+		covered, err := bs.leaderCap(ctx, bs.queries, product, leaderId, currentPrice)
+		if err != nil {
+			return err
+		}
+		snapshot.ReserveMet = reserveMet(product, covered)
+	}
+
+	snapshot.Bidders, err = bs.queries.CountBiddersByProductId(ctx, product.ID)
+	if err != nil {
+		return err
+	}
+
+	own, err := bs.queries.GetHighestBidByProductAndBidder(ctx, pgstore.GetHighestBidByProductAndBidderParams{
+		ProductID: product.ID,
+		BidderID:  user_id,
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	if err == nil {
+		snapshot.Position.Status = PositionOutbid
+		snapshot.Position.Amount = own.BidAmount
+		if leaderId == user_id {
+			snapshot.Position.Status = PositionWinning
+		}
+	}
+
+	maxBid, err := bs.queries.GetMaxBidByProductAndBidder(ctx, pgstore.GetMaxBidByProductAndBidderParams{
+		ProductID: product.ID,
+		BidderID:  user_id,
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	if err == nil {
+		snapshot.Position.MaxBid = &maxBid.MaxAmount
+	}
+
+	return nil
+}
+
+func (bs *BidService) sealedState(ctx context.Context, product pgstore.Product, user_id uuid.UUID, snapshot *AuctionSnapshot) error {
+	snapshot.MinimumBid = product.Baseprice
+
+	var err error
+	snapshot.Bidders, err = bs.queries.CountSealedBidsByProductId(ctx, product.ID)
+	if err != nil {
+		return err
+	}
+
+	own, err := bs.queries.GetSealedBidByProductAndBidder(ctx, pgstore.GetSealedBidByProductAndBidderParams{
+		ProductID: product.ID,
+		BidderID:  user_id,
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	if err == nil {
+		snapshot.Position.Status = PositionSealed
+		snapshot.Position.Amount = own.BidAmount
+	}
+
+	return nil
+}
+
+func (bs *BidService) multiUnitState(ctx context.Context, product pgstore.Product, user_id uuid.UUID, snapshot *AuctionSnapshot) error {
+	bids, err := bs.activeUnitBids(ctx, bs.queries, product.ID)
+	if err != nil {
+		return err
+	}
+
+	allocation := auction.Allocate(bids, product.Quantity, product.PricingRule)
+
+	snapshot.CurrentPrice = allocation.ClearingPrice
+	snapshot.MinimumBid = allocation.MinimumBid(product.Baseprice, product.MinIncrement)
+	snapshot.Bidders = int64(len(bids))
+
+	for _, award := range allocation.Awards {
+		if award.BidderId != user_id {
+			continue
+		}
+		snapshot.Position.Status = PositionOutbid
+		snapshot.Position.Amount = award.Amount
+		if award.Won > 0 {
+			snapshot.Position.Status = PositionWinning
+			snapshot.Position.Quantity = award.Won
+		}
+	}
+
+	return nil
+}
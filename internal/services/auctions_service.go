@@ -2,122 +2,722 @@ package services
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"log/slog"
+	"math"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/jackc/pgx/v5"
+	"github.com/mauvalente/go-bid/internal/auction"
+	"github.com/mauvalente/go-bid/internal/money"
+	"github.com/mauvalente/go-bid/internal/store/pgstore"
 )
 
 type MessageKind int
 
+// Os valores são fixos porque fazem parte do protocolo v0: um kind novo
+// recebe o próximo número livre, nunca o de outro.
 const (
 	//Request
-	PlaceBid MessageKind = iota
+	PlaceBid MessageKind = 0
 
 	// Ok/Success
-	SuccessfullyPlaceBid
+	SuccessfullyPlaceBid MessageKind = 1
 
 	// Info
-	NewBidPlaced
-	AuctionFinished
-	InvalidJSON
+	NewBidPlaced    MessageKind = 2
+	AuctionFinished MessageKind = 3
+	InvalidJSON     MessageKind = 4
 
 	//Errors
-	FailedToPlaceBid
+	FailedToPlaceBid MessageKind = 5
+
+	// Info
+	AuctionExtended MessageKind = 6
+
+	// Request / Ok / Errors do lance máximo (proxy)
+	SetMaxBid             MessageKind = 7
+	SuccessfullySetMaxBid MessageKind = 8
+	FailedToSetMaxBid     MessageKind = 9
+
+	// Request / Errors do "compre já"
+	BuyNow         MessageKind = 10
+	FailedToBuyNow MessageKind = 11
+
+	// Info
+	AuctionStarted MessageKind = 12
+
+	// Request / Errors / Info do leilão holandês
+	AcceptPrice         MessageKind = 13
+	FailedToAcceptPrice MessageKind = 14
+	PriceUpdated        MessageKind = 15
+
+	// Info dos leilões com várias unidades
+	ClearingPriceUpdated MessageKind = 16
+	BidWinning           MessageKind = 17
+	BidOutbid            MessageKind = 18
+
+	// Info
+	ReservePriceMet MessageKind = 19
+	AuctionState    MessageKind = 20
 )
 
 type Message struct {
-	Message string      `json:"message,omitempty"`
-	Kind    MessageKind `json:"kind"`
-	UserId  uuid.UUID   `json:"user_id,omitempty"`
-	Amount  float64     `json:"amount,omitempty"`
+	Message    string       `json:"message,omitempty"`
+	Kind       MessageKind  `json:"kind"`
+	UserId     uuid.UUID    `json:"user_id,omitempty"`
+	Amount     money.Amount `json:"amount,omitempty"`
+	Quantity   int32        `json:"quantity,omitempty"`
+	AuctionEnd time.Time    `json:"auction_end,omitzero"`
+	ReserveMet *bool        `json:"reserve_met,omitempty"`
+	Seq        int64        `json:"seq,omitempty"`
+
+	// RequestId vem do cliente e volta na resposta ao pedido
+	RequestId string `json:"request_id,omitempty"`
+	// BidId vem na confirmação do lance e em todo NewBidPlaced, inclusive
+	// nos do próprio usuário, para que o cliente descarte o repetido;
+	// ErrorCode vem nas recusas
+	BidId     uuid.UUID `json:"bid_id,omitzero"`
+	ErrorCode string    `json:"error_code,omitempty"`
+
+	// State só vem nas mensagens AuctionState
+	State *AuctionSnapshot `json:"state,omitempty"`
+
+	// Reply recebe a resposta da sala quando o pedido não veio de uma
+	// conexão websocket (ex: API REST).
+	Reply chan Message `json:"-"`
 }
 
+var ErrAuctionRoomClosed = errors.New("the auction room is closed")
+
 type AuctionLobby struct {
 	sync.Mutex
 	Rooms map[uuid.UUID]*AuctionRoom
 }
 
+// AuctionRoom controla o prazo com um timer próprio, e não com o deadline do
+// contexto, porque o anti-sniping pode adiar o fim do leilão. O Context só
+// serve para parar a sala.
 type AuctionRoom struct {
-	Id         uuid.UUID
-	Context    context.Context
-	Broadcast  chan Message
-	Register   chan *Client
-	Unregister chan *Client
-	Clients    map[uuid.UUID]*Client
+	Id           uuid.UUID
+	Context      context.Context
+	AuctionType  auction.Type
+	AuctionStart time.Time
+	AuctionEnd   time.Time
+	Quantity     int32
+	Broadcast    chan Message
+	Register     chan *Client
+	Unregister   chan *Client
+	// Clients guarda as conexões de cada usuário: o mesmo usuário pode estar
+	// na sala em várias abas ou dispositivos.
+	Clients map[uuid.UUID]map[*Client]struct{}
 
 	BidService BidService
+	Events     EventBus
+
+	deadline *time.Timer
+	finished bool
+	// settleRetries conta as falhas seguidas ao liquidar, para o backoff
+	settleRetries int
+
+	// history guarda os últimos eventos para quem reconecta com ?since=
+	history []Event
+
+	closing chan struct{}
+	done    chan struct{}
+
+	// leilão holandês: o preço cai a cada disparo de priceTimer
+	startPrice money.Amount
+	dutch      *auction.DutchSchedule
+	priceTimer *time.Timer
 }
 
-func NewAuctionRoom(ctx context.Context, id uuid.UUID, BidService BidService) *AuctionRoom {
+func NewAuctionRoom(ctx context.Context, product pgstore.Product, BidService BidService, Events EventBus) *AuctionRoom {
 	return &AuctionRoom{
-		Id:         id,
-		Context:    ctx,
-		Broadcast:  make(chan Message),
-		Register:   make(chan *Client),
-		Unregister: make(chan *Client),
-		Clients:    make(map[uuid.UUID]*Client),
-		BidService: BidService,
+		Id:           product.ID,
+		Context:      ctx,
+		AuctionType:  product.AuctionType,
+		AuctionStart: product.AuctionStart,
+		AuctionEnd:   product.AuctionEnd,
+		Quantity:     product.Quantity,
+		Broadcast:    make(chan Message),
+		Register:     make(chan *Client),
+		Unregister:   make(chan *Client),
+		Clients:      make(map[uuid.UUID]map[*Client]struct{}),
+		BidService:   BidService,
+		Events:       Events,
+		startPrice:   product.Baseprice,
+		dutch:        product.DutchSchedule,
+		closing:      make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+}
+
+// Submit entrega um pedido à sala e espera a resposta destinada a quem pediu.
+func (r *AuctionRoom) Submit(ctx context.Context, m Message) (Message, error) {
+	reply, err := r.Send(ctx, m)
+	if err != nil {
+		return Message{}, err
+	}
+
+	select {
+	case reply := <-reply:
+		return reply, nil
+	case <-ctx.Done():
+		return Message{}, ctx.Err()
+	}
+}
+
+// Send só entrega o pedido e devolve o canal da resposta. Sem erro, a sala
+// já recebeu o pedido e sempre responde, então quem desiste de esperar pode
+// continuar lendo o canal depois; o buffer garante que a sala não trava.
+func (r *AuctionRoom) Send(ctx context.Context, m Message) (<-chan Message, error) {
+	m.Reply = make(chan Message, 1)
+
+	select {
+	case r.Broadcast <- m:
+		return m.Reply, nil
+	case <-r.Context.Done():
+		return nil, ErrAuctionRoomClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
 func (r *AuctionRoom) registerClient(c *Client) {
 	slog.Info("New user Connected", "Client", c)
-	r.Clients[c.UserId] = c
+	if r.Clients[c.UserId] == nil {
+		r.Clients[c.UserId] = make(map[*Client]struct{})
+	}
+	r.Clients[c.UserId][c] = struct{}{}
+
+	r.replay(c)
+	r.sendState(c)
+}
+
+// replay reenvia ao cliente que reconectou os eventos que ele perdeu. O que já
+// saiu do buffer é reconstruído pela tabela de lances, então dessa parte só
+// os lances são recuperados.
+func (r *AuctionRoom) replay(c *Client) {
+	since := c.LastSeq
+	if since <= 0 {
+		return
+	}
+
+	oldest := int64(math.MaxInt64)
+	if len(r.history) > 0 {
+		oldest = r.history[0].Message.Seq
+	}
+
+	if oldest > since+1 {
+		bids, err := r.BidService.BidsSinceSeq(r.Context, r.Id, since)
+		if err != nil {
+			slog.Error("Failed to load missed bids", "auctionId", r.Id, "since", since, "error", err)
+		}
+		for _, bid := range bids {
+			if bid.Seq.Int64 >= oldest {
+				break
+			}
+			r.send(c, Message{Kind: NewBidPlaced, Message: "A new bid was placed", UserId: bid.BidderID, Amount: bid.BidAmount, Quantity: bid.Quantity, Seq: bid.Seq.Int64, BidId: bid.ID})
+		}
+	}
+
+	for _, event := range r.history {
+		if event.Message.Seq > since && event.deliversTo(c.UserId) {
+			r.send(c, event.messageFor(c.UserId))
+		}
+	}
+}
+
+func (r *AuctionRoom) remember(event Event) {
+	if len(r.history) == replayBufferSize {
+		r.history = append(r.history[:0], r.history[1:]...)
+	}
+	r.history = append(r.history, event)
+}
+
+// sendState manda a foto do leilão para quem acabou de entrar; os eventos
+// seguintes chegam depois dela porque a sala processa tudo em ordem.
+func (r *AuctionRoom) sendState(c *Client) {
+	state, err := r.BidService.AuctionState(r.Context, r.Id, c.UserId)
+	if err != nil {
+		slog.Error("Failed to load auction state", "auctionId", r.Id, "user_id", c.UserId, "error", err)
+		return
+	}
+
+	r.send(c, Message{Kind: AuctionState, Message: "Current auction state", UserId: c.UserId, State: &state})
 }
 
 func (r *AuctionRoom) unregisterClient(c *Client) {
 	slog.Info("New user Disconnected", "Client", c)
-	delete(r.Clients, c.UserId)
+	r.removeClient(c)
+}
+
+func (r *AuctionRoom) removeClient(c *Client) {
+	delete(r.Clients[c.UserId], c)
+	if len(r.Clients[c.UserId]) == 0 {
+		delete(r.Clients, c.UserId)
+	}
+}
+
+// sendToUser entrega a mensagem em todas as conexões do usuário.
+func (r *AuctionRoom) sendToUser(userId uuid.UUID, m Message) {
+	for client := range r.Clients[userId] {
+		r.send(client, m)
+	}
+}
+
+// send nunca bloqueia a sala. Um cliente com o buffer cheio é desconectado em
+// vez de perder mensagens no meio do caminho: ao reconectar com ?since= ele
+// recupera o que faltou.
+func (r *AuctionRoom) send(c *Client, m Message) {
+	if c.closed {
+		return
+	}
+
+	select {
+	case c.Send <- m:
+	default:
+		metrics.Add(metricMessagesDropped, 1)
+		metrics.Add(metricSlowClientsEvicted, 1)
+		slog.Warn("Slow client evicted", "auctionId", r.Id, "user_id", c.UserId, "buffered", len(c.Send))
+
+		r.disconnect(c, websocket.ClosePolicyViolation, "client too slow, reconnect with since")
+	}
+}
+
+// disconnect fecha o Send do cliente; o WriteEventLoop entrega o que ainda
+// estiver no buffer e termina com o close frame informado.
+func (r *AuctionRoom) disconnect(c *Client, code int, reason string) {
+	c.closed = true
+	c.closeCode = code
+	c.closeReason = reason
+	r.removeClient(c)
+	close(c.Send)
+}
+
+// Close encerra a sala sem liquidar o leilão: o pedido em andamento termina,
+// os clientes recebem o close frame de restart e Run retorna. O prazo segue
+// valendo no banco, e quem liquida é outra instância ou o próximo boot.
+func (r *AuctionRoom) Close(ctx context.Context) error {
+	select {
+	case r.closing <- struct{}{}:
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// resync trata a inscrição derrubada pelo bus: os eventos perdidos não estão
+// no histórico, então ele é descartado e os clientes são desconectados para
+// retomar com since, agora pela tabela de lances.
+func (r *AuctionRoom) resync() {
+	slog.Warn("Auction room fell behind the event bus, resyncing clients", "auctionId", r.Id)
+	r.history = nil
+
+	for _, conns := range r.Clients {
+		for client := range conns {
+			r.disconnect(client, websocket.CloseTryAgainLater, "missed events, reconnect with since")
+		}
+	}
+}
+
+func (r *AuctionRoom) closeClients() {
+	for _, conns := range r.Clients {
+		for client := range conns {
+			r.disconnect(client, websocket.CloseServiceRestart, "server restarting, reconnect")
+		}
+	}
 }
 
 func (r *AuctionRoom) broadcastMessage(m Message) {
 	slog.Info("New message received", "RoomId", r.Id, "message", m.Message, "user_id", m.UserId)
 	switch m.Kind {
 	case PlaceBid:
-		bid, err := r.BidService.PlaceBid(r.Context, r.Id, m.UserId, m.Amount)
+		if r.AuctionType.Sealed() {
+			r.placeSealedBid(m)
+			return
+		}
+		if r.Quantity > 1 {
+			r.placeMultiUnitBid(m)
+			return
+		}
+
+		var pending []Event
+		placed, err := r.BidService.PlaceBid(r.Context, r.Id, m.UserId, m.Amount, announceWith(r, &pending, func(placed PlacedBid) []Event {
+			newBidMessage := Message{Kind: NewBidPlaced, Message: "A new bid was placed", Amount: placed.Bid.BidAmount, UserId: m.UserId, ReserveMet: placed.ReserveMet, BidId: placed.Bid.ID}
+			events := []Event{{Type: EventBidPlaced, Message: newBidMessage, BidId: placed.Bid.ID}}
+			return append(events, autoBidEvents(placed.AutoBids, placed.ReserveMet, placed.ReserveReached, placed.Extended, placed.AuctionEnd)...)
+		}))
 		if err != nil {
-			if errors.Is(err, ErrBidIsTooLow) {
-				if client, ok := r.Clients[m.UserId]; ok {
-					client.Send <- Message{Kind: FailedToPlaceBid, Message: ErrBidIsTooLow.Error(), UserId: m.UserId}
-				}
-				return
-			}
+			r.sendFailure(m, FailedToPlaceBid, err)
+			return
 		}
 
-		if client, ok := r.Clients[m.UserId]; ok {
-			client.Send <- Message{Kind: SuccessfullyPlaceBid, Message: "Your bid was Successfully placed.", UserId: m.UserId}
+		r.reply(m, Message{Kind: SuccessfullyPlaceBid, Message: "Your bid was Successfully placed.", UserId: m.UserId, Amount: placed.Bid.BidAmount, Quantity: placed.Bid.Quantity, BidId: placed.Bid.ID})
+		r.publishAll(pending)
+	case SetMaxBid:
+		var pending []Event
+		result, err := r.BidService.SetMaxBid(r.Context, r.Id, m.UserId, m.Amount, announceWith(r, &pending, func(result SetMaxBidResult) []Event {
+			return autoBidEvents(result.AutoBids, result.ReserveMet, result.ReserveReached, result.Extended, result.AuctionEnd)
+		}))
+		if err != nil {
+			r.sendFailure(m, FailedToSetMaxBid, err)
+			return
 		}
 
-		for id, client := range r.Clients {
-			if id == m.UserId {
-				continue
-			}
-			newBidMessage := Message{Kind: NewBidPlaced, Message: "A new bid was placed", Amount: bid.BidAmount, UserId: m.UserId}
-			client.Send <- newBidMessage
+		// o valor máximo só volta para o próprio usuário
+		r.reply(m, Message{Kind: SuccessfullySetMaxBid, Message: "Your maximum bid was Successfully set.", UserId: m.UserId, Amount: result.MaxBid.MaxAmount})
+		r.publishAll(pending)
+	case BuyNow:
+		result, err := r.BidService.BuyNow(r.Context, r.Id, m.UserId)
+		if err != nil {
+			r.sendFailure(m, FailedToBuyNow, err)
+			return
 		}
+
+		finished := Message{Kind: AuctionFinished, Message: "The product was bought with buy it now", UserId: result.WinnerId, Amount: result.ClosingPrice, RequestId: m.RequestId}
+		if m.Reply != nil {
+			m.Reply <- finished
+		}
+		r.publish(Event{Type: EventAuctionFinished, Message: finished, ReplyTo: m.UserId})
+	case AcceptPrice:
+		result, err := r.BidService.AcceptPrice(r.Context, r.Id, m.UserId)
+		if err != nil {
+			r.sendFailure(m, FailedToAcceptPrice, err)
+			return
+		}
+
+		finished := Message{Kind: AuctionFinished, Message: "The price was accepted, we have a winner", UserId: result.WinnerId, Amount: result.ClosingPrice, RequestId: m.RequestId}
+		if m.Reply != nil {
+			m.Reply <- finished
+		}
+		r.publish(Event{Type: EventAuctionFinished, Message: finished, ReplyTo: m.UserId})
 	case InvalidJSON:
-		client, ok := r.Clients[m.UserId]
-		if !ok {
+		if _, ok := r.Clients[m.UserId]; !ok {
 			slog.Info("Client not found in hashmap", "UserId", m.UserId)
 			return
 		}
-		client.Send <- m
+		r.sendToUser(m.UserId, m)
+
+	}
+}
+
+// placeSealedBid confirma o lance só para quem o fez: em envelope fechado
+// ninguém mais recebe NewBidPlaced. O evento interno avisa que houve um lance,
+// mas sem o valor, que passa pelo NOTIFY e só pode aparecer na liquidação.
+func (r *AuctionRoom) placeSealedBid(m Message) {
+	bid, err := r.BidService.PlaceSealedBid(r.Context, r.Id, m.UserId, m.Amount)
+	if err != nil {
+		r.sendFailure(m, FailedToPlaceBid, err)
+		return
+	}
+
+	r.reply(m, Message{Kind: SuccessfullyPlaceBid, Message: "Your sealed bid was Successfully placed.", UserId: m.UserId, Amount: bid.BidAmount, BidId: bid.ID})
+	r.publish(Event{Type: EventBidPlaced, Message: Message{Kind: NewBidPlaced, Message: "A sealed bid was placed", UserId: m.UserId, BidId: bid.ID}, BidId: bid.ID, Internal: true})
+}
+
+// placeMultiUnitBid assume uma unidade quando o cliente não informa a
+// quantidade.
+func (r *AuctionRoom) placeMultiUnitBid(m Message) {
+	var pending []Event
+	placed, err := r.BidService.PlaceMultiUnitBid(r.Context, r.Id, m.UserId, m.Amount, max(m.Quantity, 1), announceWith(r, &pending, func(placed MultiUnitBidResult) []Event {
+		newBidMessage := Message{Kind: NewBidPlaced, Message: "A new bid was placed", Amount: placed.Bid.BidAmount, Quantity: placed.Bid.Quantity, UserId: m.UserId, BidId: placed.Bid.ID}
+		events := []Event{{Type: EventBidPlaced, Message: newBidMessage, BidId: placed.Bid.ID}}
+		events = append(events, allocationEvents(placed.Previous, placed.Allocation)...)
+		if placed.Extended {
+			events = append(events, extendedEvent(placed.AuctionEnd))
+		}
+		return events
+	}))
+	if err != nil {
+		r.sendFailure(m, FailedToPlaceBid, err)
+		return
+	}
+
+	r.reply(m, Message{Kind: SuccessfullyPlaceBid, Message: "Your bid was Successfully placed.", UserId: m.UserId, Amount: placed.Bid.BidAmount, Quantity: placed.Bid.Quantity, BidId: placed.Bid.ID})
+	r.publishAll(pending)
+}
+
+// allocationEvents compara a nova distribuição com a anterior e avisa só o
+// que mudou: o preço de corte para todos e, para cada participante cujo lance
+// mudou de situação, se está levando unidades ou foi superado.
+func allocationEvents(previous, allocation auction.Allocation) []Event {
+	var events []Event
+	if allocation.ClearingPrice != previous.ClearingPrice {
+		events = append(events, Event{Type: EventClearingPriceUpdated, Message: Message{Kind: ClearingPriceUpdated, Message: "The clearing price has changed", Amount: allocation.ClearingPrice}})
+	}
+
+	before := make(map[uuid.UUID]auction.Award, len(previous.Awards))
+	for _, award := range previous.Awards {
+		before[award.BidderId] = award
+	}
+
+	for _, award := range allocation.Awards {
+		if old, ok := before[award.BidderId]; ok && old.Won == award.Won && old.Price == award.Price && old.Amount == award.Amount {
+			continue
+		}
+
+		if award.Won > 0 {
+			events = append(events, Event{Type: EventUnitsWinning, To: award.BidderId, Message: Message{Kind: BidWinning, Message: "Your bid is winning units", UserId: award.BidderId, Amount: award.Price, Quantity: award.Won}})
+		} else {
+			events = append(events, Event{Type: EventUnitsOutbid, To: award.BidderId, Message: Message{Kind: BidOutbid, Message: "Your bid has been outbid", UserId: award.BidderId, Amount: award.Amount}})
+		}
+	}
+	return events
+}
+
+// announceWith monta os eventos do lance dentro da transação dele. Se o bus
+// publica na transação, a numeração e o NOTIFY saem junto com o commit; se
+// não, os eventos ficam em pending e a sala os publica depois do commit.
+func announceWith[T any](r *AuctionRoom, pending *[]Event, build func(T) []Event) Announce[T] {
+	return func(tx pgx.Tx, result T) error {
+		events := build(result)
+		for i := range events {
+			events[i].AuctionId = r.Id
+		}
+
+		if bus, ok := r.Events.(TxEventBus); ok {
+			return bus.PublishTx(r.Context, tx, events...)
+		}
+		*pending = events
+		return nil
+	}
+}
+
+func (r *AuctionRoom) publishAll(events []Event) {
+	for _, event := range events {
+		r.publish(event)
+	}
+}
+
+// publish envia o evento para as salas deste leilão em todas as instâncias,
+// esta inclusive. Se o bus falhar, ao menos os clientes locais recebem.
+func (r *AuctionRoom) publish(event Event) {
+	event.AuctionId = r.Id
+	if err := r.Events.Publish(r.Context, event); err != nil {
+		slog.Error("Failed to publish auction event", "auctionId", r.Id, "error", err)
+		r.deliver(event)
+	}
+}
+
+// deliver entrega o evento aos clientes conectados nesta instância e mantém
+// o prazo local em dia com as prorrogações feitas em qualquer instância.
+func (r *AuctionRoom) deliver(event Event) {
+	if event.Internal {
+		return
+	}
+
+	if event.Message.Seq > 0 {
+		r.remember(event)
+	}
+
+	switch event.Message.Kind {
+	case AuctionExtended:
+		if event.Message.AuctionEnd.After(r.AuctionEnd) {
+			slog.Info("Auction has been extended", "auctionId", r.Id, "auctionEnd", event.Message.AuctionEnd)
+			r.AuctionEnd = event.Message.AuctionEnd
+			r.deadline.Reset(time.Until(r.AuctionEnd))
+		}
+	case AuctionFinished:
+		r.finished = true
+	}
+
+	for id := range r.Clients {
+		if event.deliversTo(id) {
+			r.sendToUser(id, event.messageFor(id))
+		}
+	}
+}
+
+// reply responde só a quem fez o pedido: pelo canal Reply, quando existe, e
+// por todas as conexões do usuário, para que as outras abas também saibam.
+func (r *AuctionRoom) reply(request Message, response Message) {
+	response.RequestId = request.RequestId
+
+	if request.Reply != nil {
+		request.Reply <- response
+	}
+
+	r.sendToUser(request.UserId, response)
+}
+
+func (r *AuctionRoom) sendFailure(request Message, kind MessageKind, err error) {
+	userId := request.UserId
+	failed := Message{Kind: kind, Message: "could not process your request, try again later", UserId: userId, ErrorCode: ErrorCodeInternal}
+
+	var tooLow *BidTooLowError
+	if errors.As(err, &tooLow) {
+		failed.Amount = tooLow.MinimumBid
+	}
+
+	if code, ok := errorCode(err); ok {
+		failed.Message = err.Error()
+		failed.ErrorCode = code
+	} else {
+		slog.Error("Failed to process bid", "RoomId", r.Id, "user_id", userId, "error", err)
+	}
+
+	r.reply(request, failed)
+	r.publish(Event{Type: EventBidRejected, Message: failed, Internal: true})
+}
+
+// autoBidEvents anuncia os lances automáticos e, depois deles, a reserva
+// atingida e a prorrogação do prazo.
+func autoBidEvents(bids []pgstore.Bid, reserveMet *bool, reserveReached, extended bool, auctionEnd time.Time) []Event {
+	var events []Event
+	for _, bid := range bids {
+		events = append(events, Event{Type: EventBidPlaced, Message: Message{Kind: NewBidPlaced, Message: "An automatic bid was placed", Amount: bid.BidAmount, UserId: bid.BidderID, ReserveMet: reserveMet, BidId: bid.ID}, BidId: bid.ID})
+	}
+
+	if reserveReached {
+		met := true
+		events = append(events, Event{Type: EventReserveMet, Message: Message{Kind: ReservePriceMet, Message: "The reserve price has been met", ReserveMet: &met}})
+	}
+
+	if extended {
+		events = append(events, extendedEvent(auctionEnd))
+	}
+	return events
+}
+
+func extendedEvent(auctionEnd time.Time) Event {
+	return Event{Type: EventAuctionExtended, Message: Message{Kind: AuctionExtended, Message: "The auction has been extended", AuctionEnd: auctionEnd}}
+}
+
+// finishAuction liquida o leilão quando o timer do prazo dispara. Só a
+// instância que de fato liquidou publica o resultado; as outras encerram a
+// sala apenas para os seus clientes. Se o banco diz que o leilão ainda não
+// acabou, a sala continua aberta com o novo prazo; se a liquidação falha, o
+// timer é rearmado para uma nova tentativa.
+func (r *AuctionRoom) finishAuction() {
+	result, err := r.BidService.SettleAuction(context.Background(), r.Id)
+	var notEnded *AuctionNotEndedError
+	if errors.As(err, &notEnded) {
+		r.AuctionEnd = notEnded.AuctionEnd
+		r.deadline.Reset(time.Until(r.AuctionEnd))
+		return
+	}
+
+	// falha no banco não encerra a sala: tenta de novo com espera crescente
+	if err != nil {
+		delay := settleRetryDelay(r.settleRetries)
+		r.settleRetries++
+		slog.Error("Failed to settle auction, retrying", "auctionId", r.Id, "retryIn", delay, "error", err)
+		r.deadline.Reset(delay)
+		return
+	}
+	r.settleRetries = 0
+
+	emit := r.publish
+	if result.AlreadySettled {
+		emit = r.deliver
+	}
+
+	for _, event := range result.FinishedEvents() {
+		emit(event)
+	}
+}
+
+// FinishedEvents monta os eventos que anunciam o resultado do leilão: as
+// unidades de cada vencedor, quando há, e por último o AuctionFinished.
+func (result AuctionResult) FinishedEvents() []Event {
+	message := Message{Kind: AuctionFinished, Message: "Auction has been finished"}
+	var events []Event
+
+	if len(result.Awards) > 0 {
+		message.Message = "Auction has been finished, the units were awarded"
+		message.Amount = result.ClosingPrice
+		for _, award := range result.Awards {
+			events = append(events, Event{Type: EventUnitsWinning, AuctionId: result.ProductId, To: award.BidderID, Message: Message{Kind: BidWinning, Message: "You won units in this auction", UserId: award.BidderID, Amount: award.UnitPrice, Quantity: award.Quantity}})
+		}
+	} else if result.IsSold {
+		message.Message = "Auction has been finished, we have a winner"
+		message.UserId = result.WinnerId
+		message.Amount = result.ClosingPrice
+	} else if result.ReserveNotMet {
+		message.Message = "Auction has been finished, the reserve price was not met"
+		reserveMet := false
+		message.ReserveMet = &reserveMet
+	} else {
+		message.Message = "Auction has been finished without bids"
+	}
+
+	return append(events, Event{Type: EventAuctionFinished, AuctionId: result.ProductId, Message: message})
+}
+
+// settleRetryDelay dobra a espera a cada falha, até maxSettleRetryDelay.
+func settleRetryDelay(retries int) time.Duration {
+	delay := minSettleRetryDelay << min(retries, 10)
+	return min(delay, maxSettleRetryDelay)
+}
+
+func (r *AuctionRoom) priceMessage() Message {
+	price := r.dutch.PriceAt(r.startPrice, r.AuctionStart, time.Now())
+	return Message{Kind: PriceUpdated, Message: "The current price has changed", Amount: price}
+}
 
+// schedulePriceDrop arma o timer para a próxima queda de preço; quando o preço
+// chega ao piso o timer fica parado até o fim do leilão.
+func (r *AuctionRoom) schedulePriceDrop() {
+	next, ok := r.dutch.NextTick(r.startPrice, r.AuctionStart, time.Now())
+	if !ok {
+		return
+	}
+	if r.priceTimer == nil {
+		r.priceTimer = time.NewTimer(time.Until(next))
+		return
 	}
+	r.priceTimer.Reset(time.Until(next))
+}
+
+func (r *AuctionRoom) dropPrice() {
+	message := r.priceMessage()
+	slog.Info("Auction price has dropped", "auctionId", r.Id, "price", message.Amount)
+
+	// todas as instâncias calculam o mesmo preço, então a queda não passa
+	// pelo bus
+	r.deliver(Event{Type: EventPriceUpdated, AuctionId: r.Id, Message: message})
+	r.schedulePriceDrop()
+}
+
+// priceDrops é nil fora do leilão holandês, e um canal nil nunca fica pronto
+// no select.
+func (r *AuctionRoom) priceDrops() <-chan time.Time {
+	if r.priceTimer == nil {
+		return nil
+	}
+	return r.priceTimer.C
 }
 
 func (r *AuctionRoom) Run() {
 	slog.Info("Auction has begun,", "auctionId", r.Id)
+	defer close(r.done)
 
-	defer func() {
-		close(r.Broadcast)
-		close(r.Register)
-		close(r.Unregister)
-	}()
+	r.deadline = time.NewTimer(time.Until(r.AuctionEnd))
+	defer r.deadline.Stop()
+
+	if r.dutch != nil {
+		r.schedulePriceDrop()
+		if r.priceTimer != nil {
+			defer r.priceTimer.Stop()
+		}
+	}
+
+	events, unsubscribe := r.Events.Subscribe(r.Id)
+	defer func() { unsubscribe() }()
 
 	for {
 		select {
@@ -127,42 +727,112 @@ func (r *AuctionRoom) Run() {
 			r.unregisterClient(client)
 		case message := <-r.Broadcast:
 			r.broadcastMessage(message)
+		case event, ok := <-events:
+			if !ok {
+				events, unsubscribe = r.Events.Subscribe(r.Id)
+				r.resync()
+				continue
+			}
+			r.deliver(event)
+		case <-r.priceDrops():
+			r.dropPrice()
+		case <-r.deadline.C:
+			r.finishAuction()
+		case <-r.closing:
+			slog.Info("Auction room closed for shutdown.", "auctionId", r.Id)
+			r.closeClients()
+			return
 		case <-r.Context.Done():
+			slog.Info("Auction room stopped.", "auctionId", r.Id)
+			return
+		}
+
+		if r.finished {
 			slog.Info("Auction has ended.", "auctionId", r.Id)
-			for _, client := range r.Clients {
-				client.Send <- Message{Kind: AuctionFinished, Message: "Auction has been finished"}
-			}
 			return
 		}
 	}
 }
 
+// Client é uma conexão inscrita na sala. Conn é nulo nos clientes SSE, que
+// leem o Send direto no handler.
 type Client struct {
 	Room   *AuctionRoom
 	Conn   *websocket.Conn
 	Send   chan Message
 	UserId uuid.UUID
+
+	// Protocol é o subprotocolo negociado no upgrade; vazio é o v0.
+	Protocol string
+
+	// LastSeq é o último evento que o cliente recebeu antes de reconectar
+	// (?since=); zero para uma conexão nova.
+	LastSeq int64
+
+	// closed é marcado pela sala ao fechar o Send; closeCode e closeReason
+	// vão no close frame enviado pelo WriteEventLoop.
+	closed      bool
+	closeCode   int
+	closeReason string
 }
 
 func NewClient(room *AuctionRoom, conn *websocket.Conn, userId uuid.UUID) *Client {
 	return &Client{
 		Room:   room,
 		Conn:   conn,
-		Send:   make(chan Message, 512),
+		Send:   make(chan Message, sendBufferSize),
 		UserId: userId,
 	}
 }
 
 const (
+	replayBufferSize = 256
+	sendBufferSize   = 512
+
 	maxMessageSize = 512
 	readDeadLine   = 60 * time.Second
 	writeWait      = 10 * time.Second
 	pingPeriod     = (readDeadLine * 9) / 10
+
+	minSettleRetryDelay = time.Second
+	maxSettleRetryDelay = time.Minute
 )
 
+// a sala para de ler os canais quando o leilão termina, então os envios
+// precisam desistir quando o contexto da sala acaba.
+func (c *Client) dispatch(m Message) {
+	select {
+	case c.Room.Broadcast <- m:
+	case <-c.Room.Context.Done():
+	}
+}
+
+// CloseReason é o motivo informado pela sala ao fechar o Send.
+func (c *Client) CloseReason() (code int, reason string) {
+	return c.closeCode, c.closeReason
+}
+
+// Join registra o cliente na sala; falso se a sala já foi encerrada.
+func (c *Client) Join() bool {
+	select {
+	case c.Room.Register <- c:
+		return true
+	case <-c.Room.done:
+		return false
+	}
+}
+
+// Leave tira o cliente da sala.
+func (c *Client) Leave() {
+	select {
+	case c.Room.Unregister <- c:
+	case <-c.Room.Context.Done():
+	}
+}
+
 func (c *Client) ReadEventLoop() {
 	defer func() {
-		c.Room.Unregister <- c
+		c.Leave()
 		c.Conn.Close()
 	}()
 
@@ -177,26 +847,57 @@ func (c *Client) ReadEventLoop() {
 	})
 
 	for {
-		var m Message
-		m.UserId = c.UserId
-		err := c.Conn.ReadJSON(&m)
+		// só erro de transporte encerra a leitura: depois de uma falha a
+		// conexão não volta a ler, e insistir faz o gorilla entrar em panic
+		_, data, err := c.Conn.ReadMessage()
 		if err != nil {
-			if !websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived) {
 				slog.Error("Unexpected Close error", "error", err)
-				return
 			}
+			return
+		}
 
-			c.Room.Broadcast <- Message{
-				Kind:    InvalidJSON,
-				Message: "this message should be a valid json",
-				UserId:  m.UserId,
+		// conteúdo inválido não derruba a conexão: o frame já foi consumido
+		m, err := c.decode(data)
+		m.UserId = c.UserId
+		if err != nil {
+			message := "this message should be a valid json"
+			if errors.Is(err, ErrInvalidEnvelope) {
+				message = err.Error()
 			}
+			c.dispatch(Message{Kind: InvalidJSON, Message: message, UserId: c.UserId, RequestId: m.RequestId})
 			continue
 		}
-		c.Room.Broadcast <- m
+		c.dispatch(m)
 	}
 }
 
+func (c *Client) decode(data []byte) (Message, error) {
+	if c.Protocol != ProtocolV1 {
+		var m Message
+		err := json.Unmarshal(data, &m)
+		return m, err
+	}
+
+	var e Envelope
+	if err := json.Unmarshal(data, &e); err != nil {
+		return Message{}, err
+	}
+	return decodeEnvelope(e)
+}
+
+func (c *Client) write(m Message) error {
+	if c.Protocol != ProtocolV1 {
+		return c.Conn.WriteJSON(m)
+	}
+
+	e, err := EncodeEnvelope(m)
+	if err != nil {
+		return err
+	}
+	return c.Conn.WriteJSON(e)
+}
+
 func (c *Client) WriteEventLoop() {
 	ticker := time.NewTicker(pingPeriod)
 	defer func() {
@@ -208,21 +909,21 @@ func (c *Client) WriteEventLoop() {
 		select {
 		case message, ok := <-c.Send:
 			if !ok {
-				c.Conn.WriteJSON(Message{
-					Kind:    websocket.CloseMessage,
-					Message: "Closing websocket message",
-				})
+				c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+				c.Conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(c.closeCode, c.closeReason))
 				return
 			}
 			if message.Kind == AuctionFinished {
-				close(c.Send)
+				c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+				c.write(message)
+				c.Conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, message.Message))
 				return
 			}
-			c.Conn.SetReadDeadline(time.Now().Add(writeWait))
+			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
 
-			err := c.Conn.WriteJSON(message)
+			err := c.write(message)
 			if err != nil {
-				c.Room.Unregister <- c
+				c.Leave()
 				return
 
 			}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/mauvalente/go-bid/internal/auction"
+	"github.com/mauvalente/go-bid/internal/money"
+	"github.com/mauvalente/go-bid/internal/store/pgstore"
+)
+
+// Um cliente que nunca lê o Send não pode travar a sala: ele é desconectado
+// com 1008 e os demais continuam recebendo tudo.
+func TestAuctionRoomEvictsStuckClient(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := NewMemoryEventBus()
+	room := NewAuctionRoom(ctx, pgstore.Product{
+		ID:          uuid.New(),
+		AuctionType: auction.TypeEnglish,
+		AuctionEnd:  time.Now().Add(time.Hour),
+		Quantity:    1,
+	}, BidService{}, bus)
+
+	// os clientes entram direto no mapa: o Register carregaria a foto do
+	// leilão do banco
+	healthy := NewClient(room, nil, uuid.New())
+	stuck := &Client{Room: room, Send: make(chan Message, 1), UserId: uuid.New()}
+	for _, c := range []*Client{healthy, stuck} {
+		room.Clients[c.UserId] = map[*Client]struct{}{c: {}}
+	}
+
+	go room.Run()
+
+	// o Broadcast não tem buffer: passar por ele garante que a sala já está
+	// no loop e inscrita no bus
+	room.Broadcast <- Message{Kind: InvalidJSON, UserId: healthy.UserId}
+	if m := receive(t, healthy.Send); m.Kind != InvalidJSON {
+		t.Fatalf("expected the InvalidJSON reply first, got kind %d", m.Kind)
+	}
+
+	const events = 10
+	for i := range events {
+		err := bus.Publish(ctx, Event{
+			Type:      EventBidPlaced,
+			AuctionId: room.Id,
+			Message:   Message{Kind: NewBidPlaced, Amount: money.Amount(100 * (i + 1))},
+		})
+		if err != nil {
+			t.Fatalf("publish: %v", err)
+		}
+	}
+
+	for i := range events {
+		m := receive(t, healthy.Send)
+		if m.Kind != NewBidPlaced || m.Seq != int64(i+1) {
+			t.Fatalf("event %d: got kind %d seq %d", i, m.Kind, m.Seq)
+		}
+	}
+
+	// o stuck recebe só o que coube no buffer e depois tem o Send fechado
+	received := 0
+	for range stuck.Send {
+		received++
+	}
+	if received != cap(stuck.Send) {
+		t.Fatalf("stuck client received %d messages, want %d", received, cap(stuck.Send))
+	}
+	if stuck.closeCode != websocket.ClosePolicyViolation {
+		t.Fatalf("stuck client close code = %d, want %d", stuck.closeCode, websocket.ClosePolicyViolation)
+	}
+
+	cancel()
+	<-room.done
+
+	if _, ok := room.Clients[stuck.UserId]; ok {
+		t.Fatal("stuck client is still registered in the room")
+	}
+	if _, ok := room.Clients[healthy.UserId]; !ok {
+		t.Fatal("healthy client was removed from the room")
+	}
+}
+
+// Conteúdo que não decodifica (JSON quebrado, valor inválido) vira
+// InvalidJSON e a leitura continua; só erro de transporte encerra o loop.
+func TestReadEventLoopKeepsReadingAfterInvalidMessages(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	room := &AuctionRoom{
+		Context:    ctx,
+		Broadcast:  make(chan Message, 8),
+		Unregister: make(chan *Client, 1),
+	}
+	userId := uuid.New()
+
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		NewClient(room, conn, userId).ReadEventLoop()
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	frames := []string{
+		`{"kind":0,"amount":"abc"}`,
+		`{"kind":0,"amount":"1.005"}`,
+		`{"kind":0,"amount":`,
+		`{"kind":"bid"}`,
+		`{"kind":0,"amount":"10.50"}`,
+	}
+	for _, frame := range frames {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(frame)); err != nil {
+			t.Fatalf("write %s: %v", frame, err)
+		}
+	}
+
+	for _, frame := range frames[:len(frames)-1] {
+		if m := receive(t, room.Broadcast); m.Kind != InvalidJSON || m.UserId != userId {
+			t.Fatalf("frame %s: got kind %d for user %s, want InvalidJSON", frame, m.Kind, m.UserId)
+		}
+	}
+
+	m := receive(t, room.Broadcast)
+	if m.Kind != PlaceBid || m.Amount != 1050 || m.UserId != userId {
+		t.Fatalf("valid bid after invalid frames: got %+v", m)
+	}
+
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	select {
+	case <-room.Unregister:
+	case <-time.After(time.Second):
+		t.Fatal("read loop did not end after the close frame")
+	}
+}
+
+func receive(t *testing.T, ch <-chan Message) Message {
+	t.Helper()
+
+	select {
+	case m, ok := <-ch:
+		if !ok {
+			t.Fatal("channel closed")
+		}
+		return m
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a message")
+	}
+	return Message{}
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mauvalente/go-bid/internal/auction"
+	"github.com/mauvalente/go-bid/internal/money"
+	"github.com/mauvalente/go-bid/internal/store/pgstore"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+type BidOutcome string
+
+const (
+	BidOutcomeActive BidOutcome = "active"
+	BidOutcomeWon    BidOutcome = "won"
+	BidOutcomeLost   BidOutcome = "lost"
+)
+
+func (o BidOutcome) Valid() bool {
+	switch o {
+	case BidOutcomeActive, BidOutcomeWon, BidOutcomeLost:
+		return true
+	}
+	return false
+}
+
+const (
+	DefaultBidPageSize = 20
+	MaxBidPageSize     = 100
+)
+
+// BidCursor aponta para o último lance de uma página; a próxima começa logo
+// depois dele na ordem (created_at, id) decrescente.
+type BidCursor struct {
+	CreatedAt time.Time
+	Id        uuid.UUID
+}
+
+func (c BidCursor) String() string {
+	raw := fmt.Sprintf("%d:%s", c.CreatedAt.UnixMicro(), c.Id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func ParseBidCursor(s string) (BidCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return BidCursor{}, ErrInvalidCursor
+	}
+
+	micros, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return BidCursor{}, ErrInvalidCursor
+	}
+
+	v, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return BidCursor{}, ErrInvalidCursor
+	}
+	parsedId, err := uuid.Parse(id)
+	if err != nil {
+		return BidCursor{}, ErrInvalidCursor
+	}
+
+	return BidCursor{CreatedAt: time.UnixMicro(v), Id: parsedId}, nil
+}
+
+func (c *BidCursor) params() (pgtype.Timestamptz, pgtype.UUID) {
+	if c == nil {
+		return pgtype.Timestamptz{}, pgtype.UUID{}
+	}
+	return pgtype.Timestamptz{Time: c.CreatedAt, Valid: true}, pgtype.UUID{Bytes: c.Id, Valid: true}
+}
+
+// ProductBid é um lance na linha do tempo do leilão. Quem deu o lance só
+// aparece pelo nome mascarado, a não ser para ele mesmo.
+type ProductBid struct {
+	Id        uuid.UUID    `json:"id"`
+	Bidder    string       `json:"bidder"`
+	Mine      bool         `json:"mine"`
+	Amount    money.Amount `json:"amount"`
+	Quantity  int32        `json:"quantity"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type UserBid struct {
+	Id          uuid.UUID    `json:"id"`
+	ProductId   uuid.UUID    `json:"product_id"`
+	ProductName string       `json:"product_name"`
+	AuctionType auction.Type `json:"auction_type"`
+	Amount      money.Amount `json:"amount"`
+	Quantity    int32        `json:"quantity"`
+	Outcome     BidOutcome   `json:"outcome"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
+// BidPage é uma página do histórico; NextCursor vem vazio na última.
+type BidPage[T any] struct {
+	Bids       []T    `json:"bids"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// ProductBids lista os lances do leilão, do mais recente para o mais antigo.
+// Em envelope fechado os lances só existem na tabela depois da liquidação,
+// então os valores ocultos nunca aparecem aqui.
+func (bs *BidService) ProductBids(ctx context.Context, product_id, viewer_id uuid.UUID, cursor *BidCursor, pageSize int32) (BidPage[ProductBid], error) {
+	createdAt, id := cursor.params()
+	rows, err := bs.queries.GetBidsByProductId(ctx, pgstore.GetBidsByProductIdParams{
+		ProductID:       product_id,
+		CursorCreatedAt: createdAt,
+		CursorID:        id,
+		PageSize:        pageSize + 1,
+	})
+	if err != nil {
+		return BidPage[ProductBid]{}, err
+	}
+
+	page := BidPage[ProductBid]{Bids: make([]ProductBid, 0, len(rows))}
+	for i, row := range rows {
+		if i == int(pageSize) {
+			last := page.Bids[i-1]
+			page.NextCursor = BidCursor{CreatedAt: last.CreatedAt, Id: last.Id}.String()
+			break
+		}
+
+		bidder := MaskUsername(row.Username)
+		if row.BidderID == viewer_id {
+			bidder = row.Username
+		}
+		page.Bids = append(page.Bids, ProductBid{
+			Id:        row.ID,
+			Bidder:    bidder,
+			Mine:      row.BidderID == viewer_id,
+			Amount:    row.BidAmount,
+			Quantity:  row.Quantity,
+			CreatedAt: row.CreatedAt,
+		})
+	}
+
+	return page, nil
+}
+
+// UserBids lista os lances do usuário. O resultado é do leilão, não do lance:
+// quem venceu vê "won" também nos lances que foram superados pelos seus. Em
+// envelope fechado entra o lance do envelope, e não a compra do vencedor.
+func (bs *BidService) UserBids(ctx context.Context, user_id uuid.UUID, outcome BidOutcome, cursor *BidCursor, pageSize int32) (BidPage[UserBid], error) {
+	createdAt, id := cursor.params()
+	rows, err := bs.queries.GetBidsByUserId(ctx, pgstore.GetBidsByUserIdParams{
+		BidderID:        user_id,
+		Outcome:         pgtype.Text{String: string(outcome), Valid: outcome != ""},
+		CursorCreatedAt: createdAt,
+		CursorID:        id,
+		PageSize:        pageSize + 1,
+	})
+	if err != nil {
+		return BidPage[UserBid]{}, err
+	}
+
+	page := BidPage[UserBid]{Bids: make([]UserBid, 0, len(rows))}
+	for i, row := range rows {
+		if i == int(pageSize) {
+			last := page.Bids[i-1]
+			page.NextCursor = BidCursor{CreatedAt: last.CreatedAt, Id: last.Id}.String()
+			break
+		}
+
+		page.Bids = append(page.Bids, UserBid{
+			Id:          row.ID,
+			ProductId:   row.ProductID,
+			ProductName: row.ProductName,
+			AuctionType: row.AuctionType,
+			Amount:      row.BidAmount,
+			Quantity:    row.Quantity,
+			Outcome:     BidOutcome(row.Outcome),
+			CreatedAt:   row.CreatedAt,
+		})
+	}
+
+	return page, nil
+}
+
+// MaskUsername mantém só a primeira e a última letra: "jones" vira "j***s".
+func MaskUsername(username string) string {
+	first, size := utf8.DecodeRuneInString(username)
+	if size == 0 {
+		return "***"
+	}
+	if utf8.RuneCountInString(username) <= 2 {
+		return string(first) + "***"
+	}
+
+	last, _ := utf8.DecodeLastRuneInString(username)
+	return string(first) + "***" + string(last)
+}
@@ -3,56 +3,588 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mauvalente/go-bid/internal/auction"
+	"github.com/mauvalente/go-bid/internal/money"
 	"github.com/mauvalente/go-bid/internal/store/pgstore"
 )
 
+// SoftClose configura o anti-sniping: um lance nos últimos Window minutos
+// empurra o fim do leilão em Extension. Window zero desliga o recurso.
+type SoftClose struct {
+	Window    time.Duration
+	Extension time.Duration
+}
+
 type BidService struct {
-	pool    *pgxpool.Pool
-	queries *pgstore.Queries
+	pool      *pgxpool.Pool
+	queries   *pgstore.Queries
+	softClose SoftClose
+
+	// buyNowThreshold é o percentual do preço de "compre já" que o maior
+	// lance pode atingir antes da opção sumir.
+	buyNowThreshold int64
 }
 
-func NewBidService(pool *pgxpool.Pool) BidService {
+func NewBidService(pool *pgxpool.Pool, softClose SoftClose, buyNowThreshold int64) BidService {
 	return BidService{
-		pool:    pool,
-		queries: pgstore.New(pool),
+		pool:            pool,
+		queries:         pgstore.New(pool),
+		softClose:       softClose,
+		buyNowThreshold: min(max(buyNowThreshold, 0), 100),
 	}
 }
 
-var ErrBidIsTooLow = errors.New("the bid value is too low")
+var (
+	ErrBidIsTooLow        = errors.New("the bid value is too low")
+	ErrAuctionHasEnded    = errors.New("the auction has ended")
+	ErrProductAlreadySold = errors.New("the product was already sold")
+	ErrBuyNowUnavailable  = errors.New("buy it now is not available for this product")
+	ErrAuctionNotStarted  = errors.New("the auction has not started yet")
+	ErrWrongAuctionType   = errors.New("this action is not available for this auction type")
+)
+
+// BidTooLowError informa o menor lance aceito no momento, para que o cliente
+// possa sugerir o próximo valor. errors.Is(err, ErrBidIsTooLow) continua valendo.
+type BidTooLowError struct {
+	MinimumBid money.Amount
+}
+
+func (e *BidTooLowError) Error() string {
+	return fmt.Sprintf("%s, the minimum acceptable bid is %s", ErrBidIsTooLow, e.MinimumBid)
+}
+
+func (e *BidTooLowError) Is(target error) bool {
+	return target == ErrBidIsTooLow
+}
+
+// PlacedBid traz, além do lance feito pelo usuário, os lances automáticos
+// que ele disparou nos máximos (proxy) dos outros participantes.
+type PlacedBid struct {
+	Bid        pgstore.Bid
+	AutoBids   []pgstore.Bid
+	AuctionEnd time.Time
+	Extended   bool
+	ReserveMet *bool
+	// ReserveReached indica que foi este lance que atingiu a reserva
+	ReserveReached bool
+}
+
+// Announce recebe o resultado de um lance ainda dentro da transação, antes
+// do commit, para que os eventos sejam numerados e notificados junto com ele.
+// Um erro desfaz o lance. Pode ser nil.
+type Announce[T any] func(tx pgx.Tx, result T) error
+
+// PlaceBid valida e grava o lance dentro de uma transação que trava a linha
+// do produto, então lances concorrentes (inclusive de outras instâncias) são
+// serializados e comparados sempre contra o maior lance já confirmado.
+func (bs *BidService) PlaceBid(ctx context.Context, product_id, bidder_id uuid.UUID, amount money.Amount, announce Announce[PlacedBid]) (PlacedBid, error) {
+	tx, err := bs.pool.Begin(ctx)
+	if err != nil {
+		return PlacedBid{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	queries := bs.queries.WithTx(tx)
+
+	now := time.Now()
+	product, err := bs.lockOpenProduct(ctx, queries, product_id, now)
+	if err != nil {
+		return PlacedBid{}, err
+	}
+
+	if product.AuctionType != auction.TypeEnglish {
+		return PlacedBid{}, ErrWrongAuctionType
+	}
+
+	if product.Quantity > 1 {
+		return PlacedBid{}, ErrMultiUnitUnsupported
+	}
+
+	currentPrice, leaderId, err := bs.currentPrice(ctx, queries, product)
+	if err != nil {
+		return PlacedBid{}, err
+	}
+
+	if minimumBid := product.MinIncrement.MinimumBid(currentPrice); amount < minimumBid {
+		return PlacedBid{}, &BidTooLowError{MinimumBid: minimumBid}
+	}
+
+	bid, err := queries.CreateBid(ctx, pgstore.CreateBidParams{
+		ProductID: product_id,
+		BidderID:  bidder_id,
+		BidAmount: amount,
+		Quantity:  1,
+	})
+	if err != nil {
+		return PlacedBid{}, err
+	}
+
+	placed := PlacedBid{Bid: bid}
+
+	placed.AutoBids, err = bs.resolveProxyBids(ctx, queries, product)
+	if err != nil {
+		return PlacedBid{}, err
+	}
+
+	placed.AuctionEnd, placed.Extended, err = bs.applySoftClose(ctx, queries, product, now)
+	if err != nil {
+		return PlacedBid{}, err
+	}
 
-func (bs *BidService) PlaceBid(ctx context.Context, product_id, bidder_id uuid.UUID, amount float64) (pgstore.Bid, error) {
-	product, err := bs.queries.GetProductById(ctx, product_id)
+	placed.ReserveMet, placed.ReserveReached, err = bs.reserveProgress(ctx, queries, product, leaderId, currentPrice)
+	if err != nil {
+		return PlacedBid{}, err
+	}
+
+	if announce != nil {
+		if err := announce(tx, placed); err != nil {
+			return PlacedBid{}, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return PlacedBid{}, err
+	}
+
+	return placed, nil
+}
+
+// lockOpenProduct trava a linha do produto até o fim da transação e garante
+// que o leilão ainda aceita lances.
+func (bs *BidService) lockOpenProduct(ctx context.Context, queries *pgstore.Queries, product_id uuid.UUID, now time.Time) (pgstore.Product, error) {
+	product, err := queries.GetProductByIdForUpdate(ctx, product_id)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return pgstore.Bid{}, err
+			return pgstore.Product{}, ErrProductNotFound
 		}
-		return pgstore.Bid{}, err
+		return pgstore.Product{}, err
+	}
+
+	if product.IsSold {
+		return pgstore.Product{}, ErrProductAlreadySold
+	}
+
+	if product.SettledAt.Valid || !now.Before(product.AuctionEnd) {
+		return pgstore.Product{}, ErrAuctionHasEnded
+	}
+
+	if now.Before(product.AuctionStart) {
+		return pgstore.Product{}, ErrAuctionNotStarted
 	}
 
-	highestBid, err := bs.queries.GetHighestBidByProductId(ctx, product_id)
+	return product, nil
+}
+
+// currentPrice devolve o maior lance e quem o fez, ou o preço base quando
+// ainda não há lances.
+func (bs *BidService) currentPrice(ctx context.Context, queries *pgstore.Queries, product pgstore.Product) (money.Amount, uuid.UUID, error) {
+	highestBid, err := queries.GetHighestBidByProductId(ctx, product.ID)
 	if err != nil {
 		// se nao encontrou linha é a primeira a ser inserida
-		if !errors.Is(err, pgx.ErrNoRows) {
-			return pgstore.Bid{}, err
+		if errors.Is(err, pgx.ErrNoRows) {
+			return product.Baseprice, uuid.Nil, nil
+		}
+		return 0, uuid.Nil, err
+	}
+	return highestBid.BidAmount, highestBid.BidderID, nil
+}
+
+// reserveMet é nil quando o produto não tem preço de reserva, para que o
+// valor da reserva nunca precise sair do serviço.
+// leaderCap é quanto o líder aceita pagar: o próprio lance ou o seu lance
+// máximo, se for maior.
+func (bs *BidService) leaderCap(ctx context.Context, queries *pgstore.Queries, product pgstore.Product, leaderId uuid.UUID, price money.Amount) (money.Amount, error) {
+	if leaderId == uuid.Nil {
+		return price, nil
+	}
+
+	leaderMax, err := queries.GetMaxBidByProductAndBidder(ctx, pgstore.GetMaxBidByProductAndBidderParams{
+		ProductID: product.ID,
+		BidderID:  leaderId,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return price, nil
 		}
+		return 0, err
+	}
+	return max(price, leaderMax.MaxAmount), nil
+}
+
+// reserveProgress compara a reserva com o que o líder cobre antes e depois
+// de uma operação. Vale a mesma regra da liquidação: a reserva está atingida
+// quando o máximo do líder a cobre, mesmo que o lance visível esteja abaixo.
+// Só o sinal sai daqui, nunca o valor.
+func (bs *BidService) reserveProgress(ctx context.Context, queries *pgstore.Queries, product pgstore.Product, leaderBefore uuid.UUID, priceBefore money.Amount) (*bool, bool, error) {
+	if product.ReservePrice == nil {
+		return nil, false, nil
+	}
+
+	before, err := bs.leaderCap(ctx, queries, product, leaderBefore, priceBefore)
+	if err != nil {
+		return nil, false, err
+	}
+
+	price, leaderId, err := bs.currentPrice(ctx, queries, product)
+	if err != nil {
+		return nil, false, err
+	}
+	after, err := bs.leaderCap(ctx, queries, product, leaderId, price)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return reserveMet(product, after), reserveReached(product, before, after, leaderBefore != uuid.Nil), nil
+}
+
+func reserveMet(product pgstore.Product, price money.Amount) *bool {
+	if product.ReservePrice == nil {
+		return nil
+	}
+	met := price >= *product.ReservePrice
+	return &met
+}
+
+// reserveReached diz se o preço passou de abaixo da reserva para acima dela.
+func reserveReached(product pgstore.Product, before, after money.Amount, hadBids bool) bool {
+	if product.ReservePrice == nil {
+		return false
+	}
+	wasMet := hadBids && before >= *product.ReservePrice
+	return !wasMet && after >= *product.ReservePrice
+}
+
+func (bs *BidService) applySoftClose(ctx context.Context, queries *pgstore.Queries, product pgstore.Product, now time.Time) (time.Time, bool, error) {
+	if bs.softClose.Window <= 0 || product.AuctionEnd.Sub(now) > bs.softClose.Window {
+		return product.AuctionEnd, false, nil
+	}
+
+	auctionEnd := product.AuctionEnd.Add(bs.softClose.Extension)
+	err := queries.UpdateProductAuctionEnd(ctx, pgstore.UpdateProductAuctionEndParams{
+		ID:         product.ID,
+		AuctionEnd: auctionEnd,
+	})
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return auctionEnd, true, nil
+}
+
+// BuyNow vende o produto pelo preço de "compre já", gravando a compra como
+// um lance do comprador e liquidando o leilão na mesma transação.
+func (bs *BidService) BuyNow(ctx context.Context, product_id, buyer_id uuid.UUID) (AuctionResult, error) {
+	tx, err := bs.pool.Begin(ctx)
+	if err != nil {
+		return AuctionResult{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	queries := bs.queries.WithTx(tx)
+
+	product, err := bs.lockOpenProduct(ctx, queries, product_id, time.Now())
+	if err != nil {
+		return AuctionResult{}, err
+	}
+
+	if product.AuctionType != auction.TypeEnglish {
+		return AuctionResult{}, ErrWrongAuctionType
+	}
+
+	if product.Quantity > 1 {
+		return AuctionResult{}, ErrMultiUnitUnsupported
+	}
+
+	if product.BuyNowPrice == nil {
+		return AuctionResult{}, ErrBuyNowUnavailable
+	}
+	buyNowPrice := *product.BuyNowPrice
+
+	currentPrice, leaderId, err := bs.currentPrice(ctx, queries, product)
+	if err != nil {
+		return AuctionResult{}, err
+	}
+
+	threshold := money.Amount(int64(buyNowPrice) * bs.buyNowThreshold / 100)
+	if leaderId != uuid.Nil && currentPrice >= threshold {
+		return AuctionResult{}, ErrBuyNowUnavailable
+	}
+
+	result, err := bs.sellProduct(ctx, queries, product_id, buyer_id, buyNowPrice)
+	if err != nil {
+		return AuctionResult{}, err
 	}
 
-	if product.Baseprice >= amount || highestBid.BidAmount >= amount {
-		return pgstore.Bid{}, ErrBidIsTooLow
+	if err := tx.Commit(ctx); err != nil {
+		return AuctionResult{}, err
 	}
 
-	highestBid, err = bs.queries.CreateBid(ctx, pgstore.CreateBidParams{
+	return result, nil
+}
+
+// sellProduct grava a compra como um lance do comprador e liquida o produto
+// com esse lance como vencedor.
+func (bs *BidService) sellProduct(ctx context.Context, queries *pgstore.Queries, product_id, buyer_id uuid.UUID, price money.Amount) (AuctionResult, error) {
+	bid, err := queries.CreateBid(ctx, pgstore.CreateBidParams{
 		ProductID: product_id,
-		BidderID:  bidder_id,
-		BidAmount: amount,
+		BidderID:  buyer_id,
+		BidAmount: price,
+		Quantity:  1,
 	})
 	if err != nil {
-		return pgstore.Bid{}, err
+		return AuctionResult{}, err
+	}
+
+	err = bs.settleProduct(ctx, queries, pgstore.SettleProductParams{
+		ID:           product_id,
+		IsSold:       true,
+		WinningBidID: pgtype.UUID{Bytes: bid.ID, Valid: true},
+		ClosingPrice: &price,
+	})
+	if err != nil {
+		return AuctionResult{}, err
+	}
+
+	return AuctionResult{
+		ProductId:    product_id,
+		IsSold:       true,
+		WinnerId:     buyer_id,
+		WinningBidId: bid.ID,
+		ClosingPrice: price,
+	}, nil
+}
+
+// settleProduct só liquida produtos ainda abertos; o UPDATE condicional é a
+// garantia final de que um leilão tem um único vencedor.
+func (bs *BidService) settleProduct(ctx context.Context, queries *pgstore.Queries, params pgstore.SettleProductParams) error {
+	settled, err := queries.SettleProduct(ctx, params)
+	if err != nil {
+		return err
+	}
+	if settled == 0 {
+		return ErrProductAlreadySold
+	}
+	return nil
+}
+
+var ErrAuctionNotEnded = errors.New("the auction has not ended yet")
+
+// AuctionNotEndedError carrega o prazo atual, que pode ter sido estendido
+// depois que a sala armou o seu timer.
+type AuctionNotEndedError struct {
+	AuctionEnd time.Time
+}
+
+func (e *AuctionNotEndedError) Error() string {
+	return fmt.Sprintf("%s, it ends at %s", ErrAuctionNotEnded, e.AuctionEnd.Format(time.RFC3339))
+}
+
+func (e *AuctionNotEndedError) Is(target error) bool {
+	return target == ErrAuctionNotEnded
+}
+
+type AuctionResult struct {
+	ProductId     uuid.UUID
+	IsSold        bool
+	ReserveNotMet bool
+	WinnerId      uuid.UUID
+	WinningBidId  uuid.UUID
+	ClosingPrice  money.Amount
+
+	// AlreadySettled indica que outra chamada (talvez de outra instância)
+	// já tinha liquidado o leilão.
+	AlreadySettled bool
+
+	// Awards só é preenchido nos leilões com mais de uma unidade
+	Awards []pgstore.UnitAward
+}
+
+// SettleAuction encerra o leilão numa única transação. Se o produto já foi
+// liquidado, devolve o resultado gravado em vez de liquidar de novo.
+func (bs *BidService) SettleAuction(ctx context.Context, product_id uuid.UUID) (AuctionResult, error) {
+	tx, err := bs.pool.Begin(ctx)
+	if err != nil {
+		return AuctionResult{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	queries := bs.queries.WithTx(tx)
+
+	product, err := queries.GetProductByIdForUpdate(ctx, product_id)
+	if err != nil {
+		return AuctionResult{}, err
+	}
+
+	if product.SettledAt.Valid {
+		return bs.settledAuctionResult(ctx, queries, product)
+	}
+
+	if time.Now().Before(product.AuctionEnd) {
+		return AuctionResult{}, &AuctionNotEndedError{AuctionEnd: product.AuctionEnd}
 	}
 
-	return highestBid, nil
+	var result AuctionResult
+	switch {
+	case product.AuctionType.Sealed():
+		result, err = bs.settleSealedAuction(ctx, queries, product)
+	case product.Quantity > 1:
+		result, err = bs.settleMultiUnitAuction(ctx, queries, product)
+	default:
+		result, err = bs.settleHighestBid(ctx, queries, product)
+	}
+	if err != nil {
+		return AuctionResult{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return AuctionResult{}, err
+	}
+
+	return result, nil
+}
+
+// settleHighestBid liquida os leilões abertos: vence o maior lance, desde que
+// atinja a reserva.
+func (bs *BidService) settleHighestBid(ctx context.Context, queries *pgstore.Queries, product pgstore.Product) (AuctionResult, error) {
+	product_id := product.ID
+	result := AuctionResult{ProductId: product_id}
+
+	highestBid, err := queries.GetHighestBidByProductId(ctx, product_id)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return AuctionResult{}, err
+	}
+	hasBids := err == nil
+
+	// abaixo da reserva o produto só é vendido se o lance máximo do líder a
+	// cobre. Aí o sistema dá por ele um último lance no valor da reserva,
+	// que vira o lance vencedor: com o leilão encerrado, o valor já pode
+	// aparecer.
+	if hasBids && product.ReservePrice != nil && highestBid.BidAmount < *product.ReservePrice {
+		covered, err := bs.leaderCap(ctx, queries, product, highestBid.BidderID, highestBid.BidAmount)
+		if err != nil {
+			return AuctionResult{}, err
+		}
+
+		if covered >= *product.ReservePrice {
+			highestBid, err = queries.CreateBid(ctx, pgstore.CreateBidParams{
+				ProductID: product_id,
+				BidderID:  highestBid.BidderID,
+				BidAmount: *product.ReservePrice,
+				Quantity:  1,
+			})
+			if err != nil {
+				return AuctionResult{}, err
+			}
+		} else {
+			result.ReserveNotMet = true
+		}
+	}
+
+	params := pgstore.SettleProductParams{ID: product_id}
+	if hasBids && !result.ReserveNotMet {
+		params.IsSold = true
+		params.WinningBidID = pgtype.UUID{Bytes: highestBid.ID, Valid: true}
+		params.ClosingPrice = &highestBid.BidAmount
+
+		result.IsSold = true
+		result.WinnerId = highestBid.BidderID
+		result.WinningBidId = highestBid.ID
+		result.ClosingPrice = highestBid.BidAmount
+	}
+
+	if err := bs.settleProduct(ctx, queries, params); err != nil {
+		return AuctionResult{}, err
+	}
+
+	return result, nil
+}
+
+func (bs *BidService) settledAuctionResult(ctx context.Context, queries *pgstore.Queries, product pgstore.Product) (AuctionResult, error) {
+	result := AuctionResult{ProductId: product.ID, IsSold: product.IsSold, AlreadySettled: true}
+	if product.Quantity > 1 {
+		awards, err := queries.GetUnitAwardsByProductId(ctx, product.ID)
+		if err != nil {
+			return AuctionResult{}, err
+		}
+		result.Awards = awards
+	}
+	if !product.WinningBidID.Valid {
+		if product.ReservePrice != nil {
+			// liquidado sem vencedor mas com lances: a reserva não foi atingida
+			hasBids, err := bs.hasBids(ctx, queries, product)
+			if err != nil {
+				return AuctionResult{}, err
+			}
+			result.ReserveNotMet = hasBids
+		}
+		return result, nil
+	}
+
+	winningBid, err := queries.GetBidById(ctx, product.WinningBidID.Bytes)
+	if err != nil {
+		return AuctionResult{}, err
+	}
+
+	result.WinnerId = winningBid.BidderID
+	result.WinningBidId = winningBid.ID
+	if product.ClosingPrice != nil {
+		result.ClosingPrice = *product.ClosingPrice
+	}
+
+	return result, nil
+}
+
+func (bs *BidService) hasBids(ctx context.Context, queries *pgstore.Queries, product pgstore.Product) (bool, error) {
+	if product.AuctionType.Sealed() {
+		top, err := queries.GetTopSealedBids(ctx, product.ID)
+		return len(top) > 0, err
+	}
+
+	_, err := queries.GetHighestBidByProductId(ctx, product.ID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return false, err
+	}
+	return err == nil, nil
+}
+
+// SettleExpiredAuctions liquida os leilões que passaram do prazo sem ninguém
+// liquidar: os que expiraram com o servidor fora do ar ou cuja sala morreu
+// com a instância. Um produto que falha não impede os outros: o erro fica no
+// log e ele é tentado de novo na próxima chamada. Só devolve os resultados
+// que esta chamada de fato liquidou.
+func (bs *BidService) SettleExpiredAuctions(ctx context.Context) ([]AuctionResult, error) {
+	ids, err := bs.queries.GetExpiredUnsettledProductIds(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var settled []AuctionResult
+	for _, id := range ids {
+		result, err := bs.SettleAuction(ctx, id)
+		if err != nil {
+			slog.Error("Failed to settle expired auction", "productId", id, "error", err)
+			continue
+		}
+		if !result.AlreadySettled {
+			settled = append(settled, result)
+		}
+	}
+
+	return settled, nil
+}
+
+// BidsSinceSeq devolve os lances publicados depois da sequência informada,
+// para a retomada de clientes que ficaram fora além do buffer da sala.
+func (bs *BidService) BidsSinceSeq(ctx context.Context, product_id uuid.UUID, since int64) ([]pgstore.Bid, error) {
+	return bs.queries.GetBidsByProductIdSinceSeq(ctx, pgstore.GetBidsByProductIdSinceSeqParams{
+		ProductID: product_id,
+		Seq:       pgtype.Int8{Int64: since, Valid: true},
+	})
 }
@@ -0,0 +1,174 @@
+//go:build integration
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mauvalente/go-bid/internal/auction"
+	"github.com/mauvalente/go-bid/internal/money"
+	"github.com/mauvalente/go-bid/internal/store/pgstore"
+)
+
+// Roda contra um Postgres já migrado, com as mesmas GOBID_DATABASE_* da API:
+//
+//	go test -tags integration ./internal/services/
+func testPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	if os.Getenv("GOBID_DATABASE_HOST") == "" {
+		t.Skip("GOBID_DATABASE_HOST is not set")
+	}
+
+	pool, err := pgxpool.New(context.Background(), fmt.Sprintf("user=%s password=%s host=%s port=%s dbname=%s",
+		os.Getenv("GOBID_DATABASE_USER"),
+		os.Getenv("GOBID_DATABASE_PASSWORD"),
+		os.Getenv("GOBID_DATABASE_HOST"),
+		os.Getenv("GOBID_DATABASE_PORT"),
+		os.Getenv("GOBID_DATABASE_NAME"),
+	))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	if err := pool.Ping(context.Background()); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+	return pool
+}
+
+// Centenas de lances simultâneos no mesmo produto: a trava da linha precisa
+// serializá-los, então os aceitos, na ordem da sequência, sobem sempre, e os
+// outros são recusados por valor baixo.
+func TestPlaceBidConcurrentBidsAreStrictlyIncreasing(t *testing.T) {
+	const (
+		bidders = 20
+		bids    = 400
+	)
+
+	ctx := context.Background()
+	pool := testPool(t)
+	queries := pgstore.New(pool)
+	suffix := uuid.NewString()
+
+	var users []uuid.UUID
+	for i := range bidders + 1 {
+		id, err := queries.CreateUser(ctx, pgstore.CreateUserParams{
+			Username:     fmt.Sprintf("concurrency-%d-%s", i, suffix),
+			Email:        fmt.Sprintf("concurrency-%d-%s@gobid.test", i, suffix),
+			PasswordHash: []byte("not-a-real-hash"),
+			Bio:          "integration test",
+		})
+		if err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+		users = append(users, id)
+	}
+	seller, bidderIds := users[0], users[1:]
+
+	product, err := queries.CreateProduct(ctx, pgstore.CreateProductParams{
+		SellerID:     seller,
+		ProductName:  "concurrency " + suffix,
+		Description:  "integration test",
+		Baseprice:    100,
+		AuctionStart: time.Now().Add(-time.Minute),
+		AuctionEnd:   time.Now().Add(time.Hour),
+		Currency:     money.DefaultCurrency,
+		MinIncrement: auction.DefaultIncrementRule,
+		Status:       string(auction.StatusLive),
+		AuctionType:  auction.TypeEnglish,
+		Quantity:     1,
+		PricingRule:  auction.PricingUniform,
+	})
+	if err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+
+	t.Cleanup(func() {
+		pool.Exec(ctx, "DELETE FROM bids WHERE product_id = $1", product.ID)
+		pool.Exec(ctx, "DELETE FROM products WHERE id = $1", product.ID)
+		pool.Exec(ctx, "DELETE FROM users WHERE id = ANY($1)", users)
+	})
+
+	bus := NewPgEventBus(pool)
+	bs := NewBidService(pool, SoftClose{}, 50)
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		accepted int
+		failures []error
+	)
+	start := make(chan struct{})
+
+	for i := range bids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+
+			bidder := bidderIds[i%bidders]
+			amount := money.Amount(200 + rand.IntN(100_000))
+
+			_, err := bs.PlaceBid(ctx, product.ID, bidder, amount, func(tx pgx.Tx, placed PlacedBid) error {
+				return bus.PublishTx(ctx, tx, Event{Type: EventBidPlaced, AuctionId: product.ID, BidId: placed.Bid.ID})
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				accepted++
+			case !errors.Is(err, ErrBidIsTooLow):
+				failures = append(failures, err)
+			}
+		}()
+	}
+
+	close(start)
+	wg.Wait()
+
+	if len(failures) > 0 {
+		t.Fatalf("%d bids failed with unexpected errors, first: %v", len(failures), failures[0])
+	}
+	if accepted == 0 {
+		t.Fatal("no bid was accepted")
+	}
+
+	rows, err := pool.Query(ctx, "SELECT seq, bid_amount FROM bids WHERE product_id = $1 ORDER BY seq", product.ID)
+	if err != nil {
+		t.Fatalf("load bids: %v", err)
+	}
+	type placedBid struct {
+		Seq    *int64
+		Amount money.Amount
+	}
+	placed, err := pgx.CollectRows(rows, pgx.RowToStructByPos[placedBid])
+	if err != nil {
+		t.Fatalf("load bids: %v", err)
+	}
+
+	if len(placed) != accepted {
+		t.Fatalf("%d bids stored, %d accepted", len(placed), accepted)
+	}
+
+	for i, bid := range placed {
+		if bid.Seq == nil || *bid.Seq != int64(i+1) {
+			t.Fatalf("bid %d has seq %v, want %d", i, bid.Seq, i+1)
+		}
+		if i > 0 && bid.Amount <= placed[i-1].Amount {
+			t.Fatalf("bid seq %d has amount %s, not above the previous %s", *bid.Seq, bid.Amount, placed[i-1].Amount)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mauvalente/go-bid/internal/auction"
+	"github.com/mauvalente/go-bid/internal/money"
+	"github.com/mauvalente/go-bid/internal/store/pgstore"
+)
+
+// DutchPrice é o preço atual de um leilão holandês, calculado a partir do
+// relógio e não de um estado em memória.
+func DutchPrice(product pgstore.Product, now time.Time) money.Amount {
+	if product.DutchSchedule == nil {
+		return product.Baseprice
+	}
+	return product.DutchSchedule.PriceAt(product.Baseprice, product.AuctionStart, now)
+}
+
+// AcceptPrice vende o produto ao primeiro usuário que aceitar o preço atual
+// de um leilão holandês. A trava da linha do produto serializa os aceites
+// concorrentes, e só o primeiro encontra o leilão ainda aberto.
+func (bs *BidService) AcceptPrice(ctx context.Context, product_id, buyer_id uuid.UUID) (AuctionResult, error) {
+	tx, err := bs.pool.Begin(ctx)
+	if err != nil {
+		return AuctionResult{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	queries := bs.queries.WithTx(tx)
+
+	now := time.Now()
+	product, err := bs.lockOpenProduct(ctx, queries, product_id, now)
+	if err != nil {
+		return AuctionResult{}, err
+	}
+
+	if product.AuctionType != auction.TypeDutch {
+		return AuctionResult{}, ErrWrongAuctionType
+	}
+
+	result, err := bs.sellProduct(ctx, queries, product_id, buyer_id, DutchPrice(product, now))
+	if err != nil {
+		return AuctionResult{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return AuctionResult{}, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,33 @@
+package services
+
+import "errors"
+
+// ErrorCodeInternal é o código das falhas que não são regra de negócio; a
+// mensagem nesse caso é genérica.
+const ErrorCodeInternal = "internal"
+
+// errorCodes são os erros que voltam ao cliente com a mensagem original e um
+// código estável, para que ele não precise comparar textos.
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{ErrBidIsTooLow, "bid_too_low"},
+	{ErrAuctionHasEnded, "auction_ended"},
+	{ErrAuctionNotStarted, "auction_not_started"},
+	{ErrProductAlreadySold, "product_already_sold"},
+	{ErrMaxBidNotRaised, "max_bid_not_raised"},
+	{ErrBuyNowUnavailable, "buy_now_unavailable"},
+	{ErrWrongAuctionType, "wrong_auction_type"},
+	{ErrInvalidQuantity, "invalid_quantity"},
+	{ErrMultiUnitUnsupported, "multi_unit_unsupported"},
+}
+
+func errorCode(err error) (string, bool) {
+	for _, known := range errorCodes {
+		if errors.Is(err, known.err) {
+			return known.code, true
+		}
+	}
+	return "", false
+}
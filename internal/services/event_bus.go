@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type EventType string
+
+// Eventos de domínio dos leilões. Assinantes de fora da sala (webhooks,
+// notificações, métricas) devem olhar para o Type, e não para o Kind da
+// mensagem, que é detalhe do protocolo com os clientes.
+const (
+	EventBidPlaced            EventType = "bid.placed"
+	EventBidRejected          EventType = "bid.rejected"
+	EventAuctionStarted       EventType = "auction.started"
+	EventAuctionExtended      EventType = "auction.extended"
+	EventAuctionFinished      EventType = "auction.finished"
+	EventReserveMet           EventType = "auction.reserve_met"
+	EventPriceUpdated         EventType = "auction.price_updated"
+	EventClearingPriceUpdated EventType = "units.clearing_price_updated"
+	EventUnitsWinning         EventType = "units.winning"
+	EventUnitsOutbid          EventType = "units.outbid"
+)
+
+// Event é um acontecimento de um leilão, entregue aos clientes conectados em
+// qualquer instância da API. Com To preenchido vai só para aquele usuário; com
+// Except, para todos menos ele.
+//
+// O bus numera as mensagens (Message.Seq) em ordem crescente por leilão, para
+// que os clientes possam retomar de onde pararam ao reconectar.
+type Event struct {
+	Type      EventType `json:"type"`
+	AuctionId uuid.UUID `json:"auction_id"`
+	Message   Message   `json:"message"`
+	To        uuid.UUID `json:"to,omitzero"`
+	Except    uuid.UUID `json:"except,omitzero"`
+
+	// BidId liga o evento ao lance que o gerou; o lance guarda a mesma
+	// sequência, o que permite reconstruir o evento pela tabela de lances.
+	BidId uuid.UUID `json:"bid_id,omitzero"`
+
+	// ReplyTo marca o evento que também é a resposta ao pedido desse
+	// usuário; só ele recebe o RequestId da mensagem.
+	ReplyTo uuid.UUID `json:"reply_to,omitzero"`
+
+	// Internal marca eventos só para os assinantes do servidor: as salas não
+	// os repassam aos clientes (ex: lances recusados já respondidos a quem
+	// pediu, lances fechados).
+	Internal bool `json:"internal,omitempty"`
+}
+
+func (e Event) deliversTo(userId uuid.UUID) bool {
+	if e.Internal {
+		return false
+	}
+	if e.To != uuid.Nil && userId != e.To {
+		return false
+	}
+	if e.Except != uuid.Nil && userId == e.Except {
+		return false
+	}
+	return true
+}
+
+func (e Event) messageFor(userId uuid.UUID) Message {
+	m := e.Message
+	if userId != e.ReplyTo {
+		m.RequestId = ""
+	}
+	return m
+}
+
+// EventBus distribui os eventos dos leilões entre as instâncias. Cada
+// instância mantém a sua própria sala para cada leilão aberto e só entrega os
+// eventos aos seus clientes locais, inclusive os que ela mesma publicou.
+//
+// A liquidação não tem um dono fixo: toda sala arma o timer do prazo, e quem
+// conseguir a trava da linha do produto primeiro liquida o leilão e publica o
+// AuctionFinished. As demais encontram o produto já liquidado e só encerram a
+// sala localmente.
+type EventBus interface {
+	Publish(ctx context.Context, event Event) error
+	// Subscribe devolve os eventos do leilão e a função que cancela a
+	// inscrição. O canal é fechado se o inscrito fica para trás e perde
+	// eventos; aí ele precisa se inscrever de novo e ressincronizar.
+	Subscribe(auctionId uuid.UUID) (<-chan Event, func())
+	// SubscribeAll recebe os eventos de todos os leilões, com a mesma regra
+	// de fechamento do Subscribe.
+	SubscribeAll() (<-chan Event, func())
+}
+
+// TxEventBus é o bus que consegue publicar dentro da transação que gerou os
+// eventos: a numeração e a notificação só valem se ela fizer commit, e saem na
+// ordem dos commits. Sem ele, a sala publica os eventos logo depois do commit.
+type TxEventBus interface {
+	PublishTx(ctx context.Context, tx pgx.Tx, events ...Event) error
+}
+
+// allAuctions é a chave dos assinantes de todos os leilões; nenhum produto
+// tem o id nulo.
+var allAuctions = uuid.Nil
+
+// eventBufferSize absorve rajadas de eventos enquanto a sala está ocupada
+// processando um lance.
+const eventBufferSize = 256
+
+type eventSubscribers struct {
+	mu   sync.Mutex
+	subs map[uuid.UUID]map[chan Event]struct{}
+}
+
+func (s *eventSubscribers) subscribe(auctionId uuid.UUID) (<-chan Event, func()) {
+	ch := make(chan Event, eventBufferSize)
+
+	s.mu.Lock()
+	if s.subs == nil {
+		s.subs = make(map[uuid.UUID]map[chan Event]struct{})
+	}
+	if s.subs[auctionId] == nil {
+		s.subs[auctionId] = make(map[chan Event]struct{})
+	}
+	s.subs[auctionId][ch] = struct{}{}
+	s.mu.Unlock()
+
+	unsubscribe := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.subs[auctionId], ch)
+		if len(s.subs[auctionId]) == 0 {
+			delete(s.subs, auctionId)
+		}
+	}
+	return ch, unsubscribe
+}
+
+// dispatch nunca bloqueia. Um inscrito com o buffer cheio não perde o evento
+// em silêncio: ele é removido e o seu canal fechado, e cabe a ele se inscrever
+// de novo e ressincronizar.
+func (s *eventSubscribers) dispatch(event Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range []uuid.UUID{event.AuctionId, allAuctions} {
+		for ch := range s.subs[key] {
+			select {
+			case ch <- event:
+			default:
+				metrics.Add(metricBusEventsDropped, 1)
+				slog.Warn("Event subscriber evicted, events dropped", "auctionId", event.AuctionId)
+
+				delete(s.subs[key], ch)
+				if len(s.subs[key]) == 0 {
+					delete(s.subs, key)
+				}
+				close(ch)
+			}
+		}
+	}
+}
+
+// evictAll derruba todos os inscritos, quando o próprio bus perdeu eventos.
+func (s *eventSubscribers) evictAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, chans := range s.subs {
+		for ch := range chans {
+			close(ch)
+		}
+	}
+	s.subs = nil
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mauvalente/go-bid/internal/store/pgstore"
+)
+
+var (
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is still being processed")
+	ErrIdempotencyKeyMismatch   = errors.New("this idempotency key was already used with a different request")
+)
+
+// IdempotencyKeyTTL é por quanto tempo uma resposta fica guardada para
+// retries.
+const IdempotencyKeyTTL = 24 * time.Hour
+
+// StoredResponse é a resposta guardada para uma chave já concluída.
+type StoredResponse struct {
+	StatusCode int
+	Body       []byte
+}
+
+type IdempotencyService struct {
+	pool    *pgxpool.Pool
+	queries *pgstore.Queries
+}
+
+func NewIdempotencyService(pool *pgxpool.Pool) IdempotencyService {
+	return IdempotencyService{
+		pool:    pool,
+		queries: pgstore.New(pool),
+	}
+}
+
+// Reserve registra a chave antes de processar o pedido. Devolve nil quando a
+// chave é nova; para uma chave já concluída devolve a resposta guardada. Uma
+// chave reservada e não concluída fica travada até expirar, porque o pedido
+// original pode ter criado o lance antes de cair.
+func (is *IdempotencyService) Reserve(ctx context.Context, user_id uuid.UUID, key string, product_id uuid.UUID, requestHash string) (*StoredResponse, error) {
+	reserved, err := is.queries.CreateIdempotencyKey(ctx, pgstore.CreateIdempotencyKeyParams{
+		UserID:         user_id,
+		IdempotencyKey: key,
+		ProductID:      product_id,
+		RequestHash:    requestHash,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reserved == 1 {
+		return nil, nil
+	}
+
+	stored, err := is.queries.GetIdempotencyKey(ctx, pgstore.GetIdempotencyKeyParams{
+		UserID:         user_id,
+		IdempotencyKey: key,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// a chave expirou entre o insert e a leitura
+			return is.Reserve(ctx, user_id, key, product_id, requestHash)
+		}
+		return nil, err
+	}
+
+	if stored.ProductID != product_id || stored.RequestHash != requestHash {
+		return nil, ErrIdempotencyKeyMismatch
+	}
+	if !stored.StatusCode.Valid {
+		return nil, ErrIdempotencyKeyInProgress
+	}
+
+	return &StoredResponse{StatusCode: int(stored.StatusCode.Int32), Body: stored.ResponseBody}, nil
+}
+
+func (is *IdempotencyService) Complete(ctx context.Context, user_id uuid.UUID, key string, response StoredResponse) error {
+	return is.queries.CompleteIdempotencyKey(ctx, pgstore.CompleteIdempotencyKeyParams{
+		UserID:         user_id,
+		IdempotencyKey: key,
+		StatusCode:     pgtype.Int4{Int32: int32(response.StatusCode), Valid: true},
+		ResponseBody:   response.Body,
+	})
+}
+
+// Release libera a chave de um pedido que falhou sem efeito, para que o retry
+// seja processado de novo.
+func (is *IdempotencyService) Release(ctx context.Context, user_id uuid.UUID, key string) error {
+	return is.queries.DeleteIdempotencyKey(ctx, pgstore.DeleteIdempotencyKeyParams{
+		UserID:         user_id,
+		IdempotencyKey: key,
+	})
+}
+
+func (is *IdempotencyService) DeleteExpired(ctx context.Context) (int64, error) {
+	return is.queries.DeleteExpiredIdempotencyKeys(ctx, time.Now().Add(-IdempotencyKeyTTL))
+}
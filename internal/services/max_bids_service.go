@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/mauvalente/go-bid/internal/auction"
+	"github.com/mauvalente/go-bid/internal/money"
+	"github.com/mauvalente/go-bid/internal/store/pgstore"
+)
+
+var ErrMaxBidNotRaised = errors.New("the new maximum must be higher than your current maximum")
+
+// maxProxyRounds é só uma trava de segurança: cada rodada grava um lance maior
+// que o anterior, então a resolução sempre termina bem antes disso.
+const maxProxyRounds = 100
+
+type SetMaxBidResult struct {
+	MaxBid         pgstore.MaxBid
+	AutoBids       []pgstore.Bid
+	AuctionEnd     time.Time
+	Extended       bool
+	ReserveMet     *bool
+	ReserveReached bool
+}
+
+// SetMaxBid cria ou aumenta o lance máximo do usuário e deixa o sistema dar
+// lances por ele, no menor incremento necessário, até esse limite.
+func (bs *BidService) SetMaxBid(ctx context.Context, product_id, bidder_id uuid.UUID, maxAmount money.Amount, announce Announce[SetMaxBidResult]) (SetMaxBidResult, error) {
+	tx, err := bs.pool.Begin(ctx)
+	if err != nil {
+		return SetMaxBidResult{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	queries := bs.queries.WithTx(tx)
+
+	now := time.Now()
+	product, err := bs.lockOpenProduct(ctx, queries, product_id, now)
+	if err != nil {
+		return SetMaxBidResult{}, err
+	}
+
+	if product.AuctionType != auction.TypeEnglish {
+		return SetMaxBidResult{}, ErrWrongAuctionType
+	}
+
+	if product.Quantity > 1 {
+		return SetMaxBidResult{}, ErrMultiUnitUnsupported
+	}
+
+	current, err := queries.GetMaxBidByProductAndBidder(ctx, pgstore.GetMaxBidByProductAndBidderParams{
+		ProductID: product_id,
+		BidderID:  bidder_id,
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return SetMaxBidResult{}, err
+	}
+	if err == nil && maxAmount <= current.MaxAmount {
+		return SetMaxBidResult{}, ErrMaxBidNotRaised
+	}
+
+	currentPrice, leaderId, err := bs.currentPrice(ctx, queries, product)
+	if err != nil {
+		return SetMaxBidResult{}, err
+	}
+
+	if leaderId != bidder_id {
+		if minimumBid := product.MinIncrement.MinimumBid(currentPrice); maxAmount < minimumBid {
+			return SetMaxBidResult{}, &BidTooLowError{MinimumBid: minimumBid}
+		}
+	}
+
+	maxBid, err := queries.UpsertMaxBid(ctx, pgstore.UpsertMaxBidParams{
+		ProductID: product_id,
+		BidderID:  bidder_id,
+		MaxAmount: maxAmount,
+	})
+	if err != nil {
+		return SetMaxBidResult{}, err
+	}
+
+	result := SetMaxBidResult{MaxBid: maxBid, AuctionEnd: product.AuctionEnd}
+
+	result.AutoBids, err = bs.resolveProxyBids(ctx, queries, product)
+	if err != nil {
+		return SetMaxBidResult{}, err
+	}
+
+	if len(result.AutoBids) > 0 {
+		result.AuctionEnd, result.Extended, err = bs.applySoftClose(ctx, queries, product, now)
+		if err != nil {
+			return SetMaxBidResult{}, err
+		}
+	}
+
+	// o líder que sobe o próprio máximo pode atingir a reserva sem lance novo
+	result.ReserveMet, result.ReserveReached, err = bs.reserveProgress(ctx, queries, product, leaderId, currentPrice)
+	if err != nil {
+		return SetMaxBidResult{}, err
+	}
+
+	if announce != nil {
+		if err := announce(tx, result); err != nil {
+			return SetMaxBidResult{}, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return SetMaxBidResult{}, err
+	}
+
+	return result, nil
+}
+
+// resolveProxyBids disputa os lances máximos até que nenhum participante
+// consiga cobrir o lance vencedor. Só os lances gerados são devolvidos; os
+// máximos nunca saem daqui. Os lances seguem sempre o incremento normal: pular
+// direto para a reserva revelaria o seu valor.
+func (bs *BidService) resolveProxyBids(ctx context.Context, queries *pgstore.Queries, product pgstore.Product) ([]pgstore.Bid, error) {
+	var autoBids []pgstore.Bid
+	rule := product.MinIncrement
+
+	for range maxProxyRounds {
+		currentPrice, leaderId, err := bs.currentPrice(ctx, queries, product)
+		if err != nil {
+			return nil, err
+		}
+
+		leaderCap, err := bs.leaderCap(ctx, queries, product, leaderId, currentPrice)
+		if err != nil {
+			return nil, err
+		}
+
+		minimumBid := rule.MinimumBid(currentPrice)
+
+		challenger, err := queries.GetTopCompetingMaxBid(ctx, pgstore.GetTopCompetingMaxBidParams{
+			ProductID: product.ID,
+			BidderID:  leaderId,
+		})
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+
+		if err != nil || challenger.MaxAmount < minimumBid {
+			return autoBids, nil
+		}
+
+		params := pgstore.CreateBidParams{ProductID: product.ID, Quantity: 1}
+		if leaderId != uuid.Nil && leaderCap >= challenger.MaxAmount {
+			// o líder cobre o desafiante (no empate, quem chegou antes fica)
+			params.BidderID = leaderId
+			params.BidAmount = min(leaderCap, rule.MinimumBid(challenger.MaxAmount))
+		} else {
+			params.BidderID = challenger.BidderID
+			params.BidAmount = min(challenger.MaxAmount, max(minimumBid, rule.MinimumBid(leaderCap)))
+		}
+
+		bid, err := queries.CreateBid(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		autoBids = append(autoBids, bid)
+	}
+
+	return autoBids, nil
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// MemoryEventBus entrega os eventos só dentro do processo. Serve para testes e
+// para rodar uma única instância sem depender do LISTEN/NOTIFY. A sequência
+// fica em memória e os lances não a guardam, então depois de um restart a
+// retomada só conta com o que chegou ao buffer das salas.
+type MemoryEventBus struct {
+	mu          sync.Mutex
+	seq         map[uuid.UUID]int64
+	subscribers eventSubscribers
+}
+
+func NewMemoryEventBus() *MemoryEventBus {
+	return &MemoryEventBus{seq: make(map[uuid.UUID]int64)}
+}
+
+func (b *MemoryEventBus) Publish(ctx context.Context, event Event) error {
+	// o lock cobre a entrega para que os eventos saiam na ordem da sequência
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// eventos internos não chegam aos clientes e ficam fora da sequência
+	if !event.Internal {
+		b.seq[event.AuctionId]++
+		event.Message.Seq = b.seq[event.AuctionId]
+	}
+
+	b.subscribers.dispatch(event)
+	return nil
+}
+
+func (b *MemoryEventBus) Subscribe(auctionId uuid.UUID) (<-chan Event, func()) {
+	return b.subscribers.subscribe(auctionId)
+}
+
+func (b *MemoryEventBus) SubscribeAll() (<-chan Event, func()) {
+	return b.subscribers.subscribe(allAuctions)
+}
@@ -0,0 +1,15 @@
+package services
+
+import "expvar"
+
+// metrics fica exposto em /debug/vars.
+var metrics = expvar.NewMap("gobid")
+
+const (
+	// mensagens que não couberam no buffer de um cliente lento
+	metricMessagesDropped = "room_messages_dropped"
+	// clientes desconectados por não acompanharem a sala
+	metricSlowClientsEvicted = "room_slow_clients_evicted"
+	// inscrições do bus derrubadas por ficarem com o buffer cheio
+	metricBusEventsDropped = "bus_events_dropped"
+)
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mauvalente/go-bid/internal/auction"
+	"github.com/mauvalente/go-bid/internal/money"
+	"github.com/mauvalente/go-bid/internal/store/pgstore"
+)
+
+var (
+	ErrInvalidQuantity      = errors.New("the requested quantity is not available for this product")
+	ErrMultiUnitUnsupported = errors.New("this action is not available for multi-unit auctions")
+)
+
+type MultiUnitBidResult struct {
+	Bid        pgstore.Bid
+	Allocation auction.Allocation
+	// Previous é a distribuição antes do lance, para avisar só quem mudou
+	Previous   auction.Allocation
+	AuctionEnd time.Time
+	Extended   bool
+}
+
+// PlaceMultiUnitBid grava o lance por unidade do usuário, que substitui o
+// lance anterior dele, e devolve a nova distribuição das unidades.
+func (bs *BidService) PlaceMultiUnitBid(ctx context.Context, product_id, bidder_id uuid.UUID, amount money.Amount, quantity int32, announce Announce[MultiUnitBidResult]) (MultiUnitBidResult, error) {
+	tx, err := bs.pool.Begin(ctx)
+	if err != nil {
+		return MultiUnitBidResult{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	queries := bs.queries.WithTx(tx)
+
+	now := time.Now()
+	product, err := bs.lockOpenProduct(ctx, queries, product_id, now)
+	if err != nil {
+		return MultiUnitBidResult{}, err
+	}
+
+	if product.Quantity <= 1 {
+		return MultiUnitBidResult{}, ErrWrongAuctionType
+	}
+
+	if quantity <= 0 || quantity > product.Quantity {
+		return MultiUnitBidResult{}, ErrInvalidQuantity
+	}
+
+	bids, err := bs.activeUnitBids(ctx, queries, product_id)
+	if err != nil {
+		return MultiUnitBidResult{}, err
+	}
+
+	previous := auction.Allocate(bids, product.Quantity, product.PricingRule)
+
+	// o lance vigente do próprio usuário será substituído, então não conta
+	competing := make([]auction.UnitBid, 0, len(bids))
+	for _, bid := range bids {
+		if bid.BidderId != bidder_id {
+			competing = append(competing, bid)
+		}
+	}
+
+	allocation := auction.Allocate(competing, product.Quantity, product.PricingRule)
+	if minimumBid := allocation.MinimumBid(product.Baseprice, product.MinIncrement); amount < minimumBid {
+		return MultiUnitBidResult{}, &BidTooLowError{MinimumBid: minimumBid}
+	}
+
+	bid, err := queries.CreateBid(ctx, pgstore.CreateBidParams{
+		ProductID: product_id,
+		BidderID:  bidder_id,
+		BidAmount: amount,
+		Quantity:  quantity,
+	})
+	if err != nil {
+		return MultiUnitBidResult{}, err
+	}
+
+	bids, err = bs.activeUnitBids(ctx, queries, product_id)
+	if err != nil {
+		return MultiUnitBidResult{}, err
+	}
+
+	result := MultiUnitBidResult{
+		Bid:        bid,
+		Allocation: auction.Allocate(bids, product.Quantity, product.PricingRule),
+		Previous:   previous,
+	}
+
+	result.AuctionEnd, result.Extended, err = bs.applySoftClose(ctx, queries, product, now)
+	if err != nil {
+		return MultiUnitBidResult{}, err
+	}
+
+	if announce != nil {
+		if err := announce(tx, result); err != nil {
+			return MultiUnitBidResult{}, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return MultiUnitBidResult{}, err
+	}
+
+	return result, nil
+}
+
+// activeUnitBids devolve o último lance de cada participante em ordem de
+// prioridade: maior preço primeiro e, no empate, o mais antigo.
+func (bs *BidService) activeUnitBids(ctx context.Context, queries *pgstore.Queries, product_id uuid.UUID) ([]auction.UnitBid, error) {
+	latest, err := queries.GetLatestBidsByProductId(ctx, product_id)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(latest, func(i, j int) bool {
+		if latest[i].BidAmount != latest[j].BidAmount {
+			return latest[i].BidAmount > latest[j].BidAmount
+		}
+		return latest[i].CreatedAt.Before(latest[j].CreatedAt)
+	})
+
+	bids := make([]auction.UnitBid, 0, len(latest))
+	for _, bid := range latest {
+		bids = append(bids, auction.UnitBid{
+			BidId:    bid.ID,
+			BidderId: bid.BidderID,
+			Amount:   bid.BidAmount,
+			Quantity: bid.Quantity,
+		})
+	}
+	return bids, nil
+}
+
+// settleMultiUnitAuction grava uma premiação por vencedor. No produto fica o
+// maior lance vencedor e o preço de corte.
+func (bs *BidService) settleMultiUnitAuction(ctx context.Context, queries *pgstore.Queries, product pgstore.Product) (AuctionResult, error) {
+	bids, err := bs.activeUnitBids(ctx, queries, product.ID)
+	if err != nil {
+		return AuctionResult{}, err
+	}
+
+	allocation := auction.Allocate(bids, product.Quantity, product.PricingRule)
+
+	result := AuctionResult{ProductId: product.ID}
+	params := pgstore.SettleProductParams{ID: product.ID}
+
+	for _, award := range allocation.Awards {
+		if award.Won == 0 {
+			continue
+		}
+		err := queries.CreateUnitAward(ctx, pgstore.CreateUnitAwardParams{
+			ProductID: product.ID,
+			BidID:     award.BidId,
+			BidderID:  award.BidderId,
+			Quantity:  award.Won,
+			UnitPrice: award.Price,
+		})
+		if err != nil {
+			return AuctionResult{}, err
+		}
+
+		if !result.IsSold {
+			params.IsSold = true
+			params.WinningBidID = pgtype.UUID{Bytes: award.BidId, Valid: true}
+			params.ClosingPrice = &allocation.ClearingPrice
+
+			result.IsSold = true
+			result.WinnerId = award.BidderId
+			result.WinningBidId = award.BidId
+			result.ClosingPrice = allocation.ClearingPrice
+		}
+	}
+
+	if err := bs.settleProduct(ctx, queries, params); err != nil {
+		return AuctionResult{}, err
+	}
+
+	result.Awards, err = queries.GetUnitAwardsByProductId(ctx, product.ID)
+	if err != nil {
+		return AuctionResult{}, err
+	}
+
+	return result, nil
+}
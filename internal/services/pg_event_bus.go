@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mauvalente/go-bid/internal/store/pgstore"
+)
+
+const (
+	pgEventsChannel   = "gobid_auction_events"
+	pgListenRetryWait = 2 * time.Second
+)
+
+// publishEventSQL numera e notifica na mesma instrução: a trava da linha do
+// produto segura o próximo evento até o commit, e o Postgres entrega os
+// NOTIFY na ordem dos commits, então a sequência chega em ordem a todas as
+// instâncias. Os eventos de um lance usam PublishTx, para que a numeração
+// aconteça na transação do próprio lance.
+const publishEventSQL = `
+WITH next AS (
+    UPDATE products SET event_seq = event_seq + 1
+    WHERE id = $1
+    RETURNING event_seq
+), bid AS (
+    UPDATE bids SET seq = (SELECT event_seq FROM next)
+    WHERE id = $3
+)
+SELECT pg_notify($4, jsonb_set($2::jsonb, '{message,seq}', to_jsonb(event_seq))::text)
+FROM next`
+
+// publishInternalEventSQL só notifica: eventos internos não chegam aos
+// clientes, então não recebem sequência nem tocam na linha do produto.
+const publishInternalEventSQL = `SELECT pg_notify($1, $2)`
+
+// PgEventBus publica os eventos com NOTIFY e recebe os de todas as instâncias
+// com LISTEN numa conexão dedicada do pool.
+type PgEventBus struct {
+	pool        *pgxpool.Pool
+	subscribers eventSubscribers
+}
+
+func NewPgEventBus(pool *pgxpool.Pool) *PgEventBus {
+	return &PgEventBus{pool: pool}
+}
+
+func (b *PgEventBus) Publish(ctx context.Context, event Event) error {
+	return publishEvent(ctx, b.pool, event)
+}
+
+// PublishTx numera e notifica os eventos dentro da transação: o NOTIFY só é
+// entregue no commit, junto com o lance.
+func (b *PgEventBus) PublishTx(ctx context.Context, tx pgx.Tx, events ...Event) error {
+	for _, event := range events {
+		if err := publishEvent(ctx, tx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func publishEvent(ctx context.Context, db pgstore.DBTX, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	if event.Internal {
+		_, err = db.Exec(ctx, publishInternalEventSQL, pgEventsChannel, string(payload))
+		return err
+	}
+
+	bidId := pgtype.UUID{Bytes: event.BidId, Valid: event.BidId != uuid.Nil}
+	_, err = db.Exec(ctx, publishEventSQL, event.AuctionId, string(payload), bidId, pgEventsChannel)
+	return err
+}
+
+func (b *PgEventBus) Subscribe(auctionId uuid.UUID) (<-chan Event, func()) {
+	return b.subscribers.subscribe(auctionId)
+}
+
+func (b *PgEventBus) SubscribeAll() (<-chan Event, func()) {
+	return b.subscribers.subscribe(allAuctions)
+}
+
+// Listen recebe as notificações até o contexto acabar, reconectando quando a
+// conexão cai. Eventos publicados durante uma queda são perdidos, então na
+// reconexão todos os inscritos são derrubados para ressincronizar.
+func (b *PgEventBus) Listen(ctx context.Context) {
+	for reconnect := false; ; reconnect = true {
+		err := b.listen(ctx, reconnect)
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Error("Event bus connection lost, reconnecting", "error", err)
+
+		select {
+		case <-time.After(pgListenRetryWait):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (b *PgEventBus) listen(ctx context.Context, reconnect bool) error {
+	pooled, err := b.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	// a conexão sai do pool para que o LISTEN não vaze para outros usos
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgEventsChannel); err != nil {
+		return err
+	}
+
+	// só depois do LISTEN: quem se inscrever de novo não perde mais nada
+	if reconnect {
+		b.subscribers.evictAll()
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var event Event
+		if err := json.Unmarshal([]byte(notification.Payload), &event); err != nil {
+			slog.Error("Invalid event received", "payload", notification.Payload, "error", err)
+			continue
+		}
+
+		b.subscribers.dispatch(event)
+	}
+}
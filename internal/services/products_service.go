@@ -8,6 +8,8 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mauvalente/go-bid/internal/auction"
+	"github.com/mauvalente/go-bid/internal/money"
 	"github.com/mauvalente/go-bid/internal/store/pgstore"
 )
 
@@ -23,25 +25,35 @@ func NewProductService(pool *pgxpool.Pool) ProductService {
 	}
 }
 
-func (ps *ProductService) CreateProduct(
-	ctx context.Context,
-	sellerId uuid.UUID,
-	productName, description string,
-	baseprice float64,
-	auctionEnd time.Time,
-) (uuid.UUID, error) {
-	id, err := ps.queries.CreateProduct(ctx, pgstore.CreateProductParams{
-		SellerID:    sellerId,
-		ProductName: productName,
-		Description: description,
-		Baseprice:   baseprice,
-		AuctionEnd:  auctionEnd,
-		IsSold:      false,
-	})
+func (ps *ProductService) CreateProduct(ctx context.Context, params pgstore.CreateProductParams) (pgstore.Product, error) {
+	if params.Currency == "" {
+		params.Currency = money.DefaultCurrency
+	}
+	if params.MinIncrement.Kind == "" {
+		params.MinIncrement = auction.DefaultIncrementRule
+	}
+	if params.AuctionType == "" {
+		params.AuctionType = auction.TypeEnglish
+	}
+	if params.Quantity == 0 {
+		params.Quantity = 1
+	}
+	if params.PricingRule == "" {
+		params.PricingRule = auction.PricingUniform
+	}
+
+	now := time.Now()
+	if params.AuctionStart.IsZero() {
+		params.AuctionStart = now
+	}
+	params.Status = string(auction.InitialStatus(params.AuctionStart, now))
+	params.IsSold = false
+
+	product, err := ps.queries.CreateProduct(ctx, params)
 	if err != nil {
-		return uuid.UUID{}, err
+		return pgstore.Product{}, err
 	}
-	return id, nil
+	return product, nil
 }
 
 var ErrProductNotFound = errors.New("product not found")
@@ -67,3 +79,9 @@ func (ps *ProductService) GetAllAvailableProducts(ctx context.Context) ([]pgstor
 	}
 	return products, nil
 }
+
+// StartDueAuctions muda para "live" os leilões agendados cujo início já
+// chegou e devolve os ids alterados.
+func (ps *ProductService) StartDueAuctions(ctx context.Context) ([]uuid.UUID, error) {
+	return ps.queries.StartDueAuctions(ctx)
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mauvalente/go-bid/internal/money"
+)
+
+// ProtocolV1 é negociado pelo header Sec-WebSocket-Protocol. Sem ele a conexão
+// fala o v0: a própria Message, com o Kind numérico.
+const ProtocolV1 = "gobid.v1"
+
+// ProtocolV1Schema é o JSON Schema dos envelopes do v1, servido pela API.
+//
+//go:embed protocol_v1.schema.json
+var ProtocolV1Schema []byte
+
+var ErrInvalidEnvelope = errors.New("invalid message envelope")
+
+// Envelope é o formato das mensagens do v1. O payload de cada tipo está
+// descrito no schema.
+type Envelope struct {
+	Type      string          `json:"type"`
+	RequestId string          `json:"request_id,omitempty"`
+	Seq       int64           `json:"seq,omitempty"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+}
+
+type envelopePayload struct {
+	Message    string           `json:"message,omitempty"`
+	UserId     uuid.UUID        `json:"user_id,omitzero"`
+	Amount     money.Amount     `json:"amount,omitempty"`
+	Quantity   int32            `json:"quantity,omitempty"`
+	AuctionEnd time.Time        `json:"auction_end,omitzero"`
+	ReserveMet *bool            `json:"reserve_met,omitempty"`
+	State      *AuctionSnapshot `json:"state,omitempty"`
+	BidId      uuid.UUID        `json:"bid_id,omitzero"`
+	ErrorCode  string           `json:"error_code,omitempty"`
+}
+
+var messageTypes = map[MessageKind]string{
+	PlaceBid:              "bid.place",
+	SuccessfullyPlaceBid:  "bid.accepted",
+	NewBidPlaced:          "bid.placed",
+	AuctionFinished:       "auction.finished",
+	InvalidJSON:           "error.invalid_message",
+	FailedToPlaceBid:      "bid.rejected",
+	AuctionExtended:       "auction.extended",
+	SetMaxBid:             "max_bid.set",
+	SuccessfullySetMaxBid: "max_bid.accepted",
+	FailedToSetMaxBid:     "max_bid.rejected",
+	BuyNow:                "buy_now.request",
+	FailedToBuyNow:        "buy_now.rejected",
+	AuctionStarted:        "auction.started",
+	AcceptPrice:           "price.accept",
+	FailedToAcceptPrice:   "price.rejected",
+	PriceUpdated:          "auction.price_updated",
+	ClearingPriceUpdated:  "units.clearing_price_updated",
+	BidWinning:            "units.winning",
+	BidOutbid:             "units.outbid",
+	ReservePriceMet:       "auction.reserve_met",
+	AuctionState:          "auction.state",
+}
+
+// só estes tipos podem vir do cliente
+var requestKinds = map[string]MessageKind{
+	messageTypes[PlaceBid]:    PlaceBid,
+	messageTypes[SetMaxBid]:   SetMaxBid,
+	messageTypes[BuyNow]:      BuyNow,
+	messageTypes[AcceptPrice]: AcceptPrice,
+}
+
+// EncodeEnvelope converte a mensagem para o v1; é também o data dos eventos
+// SSE.
+func EncodeEnvelope(m Message) (Envelope, error) {
+	payload, err := json.Marshal(envelopePayload{
+		Message:    m.Message,
+		UserId:     m.UserId,
+		Amount:     m.Amount,
+		Quantity:   m.Quantity,
+		AuctionEnd: m.AuctionEnd,
+		ReserveMet: m.ReserveMet,
+		State:      m.State,
+		BidId:      m.BidId,
+		ErrorCode:  m.ErrorCode,
+	})
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	return Envelope{Type: messageTypes[m.Kind], RequestId: m.RequestId, Seq: m.Seq, Payload: payload}, nil
+}
+
+func decodeEnvelope(e Envelope) (Message, error) {
+	kind, ok := requestKinds[e.Type]
+	if !ok {
+		return Message{RequestId: e.RequestId}, fmt.Errorf("%w: unknown type %q", ErrInvalidEnvelope, e.Type)
+	}
+
+	var payload envelopePayload
+	if len(e.Payload) > 0 {
+		if err := json.Unmarshal(e.Payload, &payload); err != nil {
+			return Message{RequestId: e.RequestId}, fmt.Errorf("%w: %s", ErrInvalidEnvelope, err)
+		}
+	}
+
+	return Message{Kind: kind, RequestId: e.RequestId, Amount: payload.Amount, Quantity: payload.Quantity}, nil
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mauvalente/go-bid/internal/auction"
+	"github.com/mauvalente/go-bid/internal/money"
+	"github.com/mauvalente/go-bid/internal/store/pgstore"
+)
+
+// PlaceSealedBid grava ou revisa o único lance fechado do usuário. O valor
+// pode subir ou descer até o fim do leilão, mas nunca abaixo do preço base.
+func (bs *BidService) PlaceSealedBid(ctx context.Context, product_id, bidder_id uuid.UUID, amount money.Amount) (pgstore.SealedBid, error) {
+	tx, err := bs.pool.Begin(ctx)
+	if err != nil {
+		return pgstore.SealedBid{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	queries := bs.queries.WithTx(tx)
+
+	product, err := bs.lockOpenProduct(ctx, queries, product_id, time.Now())
+	if err != nil {
+		return pgstore.SealedBid{}, err
+	}
+
+	if !product.AuctionType.Sealed() {
+		return pgstore.SealedBid{}, ErrWrongAuctionType
+	}
+
+	if amount < product.Baseprice {
+		return pgstore.SealedBid{}, &BidTooLowError{MinimumBid: product.Baseprice}
+	}
+
+	bid, err := queries.UpsertSealedBid(ctx, pgstore.UpsertSealedBidParams{
+		ProductID: product_id,
+		BidderID:  bidder_id,
+		BidAmount: amount,
+	})
+	if err != nil {
+		return pgstore.SealedBid{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return pgstore.SealedBid{}, err
+	}
+
+	return bid, nil
+}
+
+// settleSealedAuction abre os envelopes: o maior lance vence (no empate, quem
+// fechou o valor primeiro) e a compra é gravada na tabela de lances com o
+// preço efetivamente pago.
+func (bs *BidService) settleSealedAuction(ctx context.Context, queries *pgstore.Queries, product pgstore.Product) (AuctionResult, error) {
+	top, err := queries.GetTopSealedBids(ctx, product.ID)
+	if err != nil {
+		return AuctionResult{}, err
+	}
+
+	result := AuctionResult{ProductId: product.ID}
+	if len(top) > 0 && product.ReservePrice != nil && top[0].BidAmount < *product.ReservePrice {
+		result.ReserveNotMet = true
+	}
+
+	if len(top) == 0 || result.ReserveNotMet {
+		if err := bs.settleProduct(ctx, queries, pgstore.SettleProductParams{ID: product.ID}); err != nil {
+			return AuctionResult{}, err
+		}
+		return result, nil
+	}
+
+	return bs.sellProduct(ctx, queries, product.ID, top[0].BidderID, sealedClosingPrice(product, top))
+}
+
+// sealedClosingPrice: no primeiro preço o vencedor paga o próprio lance; no
+// Vickrey paga o segundo maior, sem ficar abaixo do preço base nem da reserva.
+func sealedClosingPrice(product pgstore.Product, top []pgstore.SealedBid) money.Amount {
+	winning := top[0].BidAmount
+	if product.AuctionType != auction.TypeVickrey {
+		return winning
+	}
+
+	price := product.Baseprice
+	if len(top) > 1 {
+		price = max(price, top[1].BidAmount)
+	}
+	if product.ReservePrice != nil {
+		price = max(price, *product.ReservePrice)
+	}
+	return min(price, winning)
+}
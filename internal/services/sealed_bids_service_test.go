@@ -0,0 +1,46 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/mauvalente/go-bid/internal/auction"
+	"github.com/mauvalente/go-bid/internal/money"
+	"github.com/mauvalente/go-bid/internal/store/pgstore"
+)
+
+func TestSealedClosingPrice(t *testing.T) {
+	reserve := func(amount money.Amount) *money.Amount { return &amount }
+	sealed := func(amounts ...money.Amount) []pgstore.SealedBid {
+		bids := make([]pgstore.SealedBid, len(amounts))
+		for i, amount := range amounts {
+			bids[i].BidAmount = amount
+		}
+		return bids
+	}
+
+	tests := []struct {
+		name    string
+		kind    auction.Type
+		reserve *money.Amount
+		top     []pgstore.SealedBid
+		want    money.Amount
+	}{
+		{name: "first price pays its own bid", kind: auction.TypeSealedFirstPrice, top: sealed(900, 700), want: 900},
+		{name: "first price ignores the reserve", kind: auction.TypeSealedFirstPrice, reserve: reserve(800), top: sealed(900, 700), want: 900},
+		{name: "vickrey pays the second bid", kind: auction.TypeVickrey, top: sealed(900, 700), want: 700},
+		{name: "vickrey tie pays the same amount", kind: auction.TypeVickrey, top: sealed(900, 900), want: 900},
+		{name: "vickrey single bid pays the base price", kind: auction.TypeVickrey, top: sealed(900), want: 500},
+		{name: "vickrey never goes below the base price", kind: auction.TypeVickrey, top: sealed(900, 300), want: 500},
+		{name: "vickrey never goes below the reserve", kind: auction.TypeVickrey, reserve: reserve(800), top: sealed(900, 700), want: 800},
+		{name: "vickrey never goes above the winning bid", kind: auction.TypeVickrey, top: sealed(400), want: 400},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			product := pgstore.Product{AuctionType: tt.kind, Baseprice: 500, ReservePrice: tt.reserve}
+			if got := sealedClosingPrice(product, tt.top); got != tt.want {
+				t.Fatalf("sealedClosingPrice = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -15,8 +16,17 @@ import (
 var (
 	ErrDuplicatedEmailOrUsername = errors.New("username or email already exists")
 	ErrInvalidCredentials        = errors.New("invalid credentials")
+	ErrUserNotFound              = errors.New("user not found")
 )
 
+// PublicProfile é o que qualquer visitante pode ver de um usuário.
+type PublicProfile struct {
+	Id        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	Bio       string    `json:"bio"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type UserService struct {
 	pool    *pgxpool.Pool
 	queries *pgstore.Queries
@@ -71,3 +81,20 @@ func (us *UserService) AuthenticateUser(ctx context.Context, email, password str
 	}
 	return user.ID, nil
 }
+
+func (us *UserService) GetPublicProfile(ctx context.Context, user_id uuid.UUID) (PublicProfile, error) {
+	user, err := us.queries.GetUserById(ctx, user_id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return PublicProfile{}, ErrUserNotFound
+		}
+		return PublicProfile{}, err
+	}
+
+	return PublicProfile{
+		Id:        user.ID,
+		Username:  user.Username,
+		Bio:       user.Bio,
+		CreatedAt: user.CreatedAt,
+	}, nil
+}
@@ -7,24 +7,58 @@ package pgstore
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mauvalente/go-bid/internal/auction"
+	"github.com/mauvalente/go-bid/internal/money"
 )
 
+const countBiddersByProductId = `-- name: CountBiddersByProductId :one
+SELECT COUNT(DISTINCT bidder_id) FROM bids
+WHERE product_id = $1
+`
+
+func (q *Queries) CountBiddersByProductId(ctx context.Context, productID uuid.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countBiddersByProductId, productID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countBidsByProductId = `-- name: CountBidsByProductId :one
+SELECT COUNT(*) FROM bids
+WHERE product_id = $1
+`
+
+func (q *Queries) CountBidsByProductId(ctx context.Context, productID uuid.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countBidsByProductId, productID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createBid = `-- name: CreateBid :one
-INSERT INTO bids ("product_id", "bidder_id", "bid_amount")
-VALUES ($1, $2, $3)
-RETURNING id, product_id, bidder_id, bid_amount, created_at
+INSERT INTO bids ("product_id", "bidder_id", "bid_amount", "quantity")
+VALUES ($1, $2, $3, $4)
+RETURNING id, product_id, bidder_id, bid_amount, created_at, quantity, seq
 `
 
 type CreateBidParams struct {
-	ProductID uuid.UUID `json:"product_id"`
-	BidderID  uuid.UUID `json:"bidder_id"`
-	BidAmount float64   `json:"bid_amount"`
+	ProductID uuid.UUID    `json:"product_id"`
+	BidderID  uuid.UUID    `json:"bidder_id"`
+	BidAmount money.Amount `json:"bid_amount"`
+	Quantity  int32        `json:"quantity"`
 }
 
 func (q *Queries) CreateBid(ctx context.Context, arg CreateBidParams) (Bid, error) {
-	row := q.db.QueryRow(ctx, createBid, arg.ProductID, arg.BidderID, arg.BidAmount)
+	row := q.db.QueryRow(ctx, createBid,
+		arg.ProductID,
+		arg.BidderID,
+		arg.BidAmount,
+		arg.Quantity,
+	)
 	var i Bid
 	err := row.Scan(
 		&i.ID,
@@ -32,12 +66,14 @@ func (q *Queries) CreateBid(ctx context.Context, arg CreateBidParams) (Bid, erro
 		&i.BidderID,
 		&i.BidAmount,
 		&i.CreatedAt,
+		&i.Quantity,
+		&i.Seq,
 	)
 	return i, err
 }
 
 const getBidById = `-- name: GetBidById :one
-SELECT id, product_id, bidder_id, bid_amount, created_at FROM bids
+SELECT id, product_id, bidder_id, bid_amount, created_at, quantity, seq FROM bids
 WHERE id = $1
 `
 
@@ -50,18 +86,84 @@ func (q *Queries) GetBidById(ctx context.Context, id uuid.UUID) (Bid, error) {
 		&i.BidderID,
 		&i.BidAmount,
 		&i.CreatedAt,
+		&i.Quantity,
+		&i.Seq,
 	)
 	return i, err
 }
 
 const getBidsByProductId = `-- name: GetBidsByProductId :many
-SELECT id, product_id, bidder_id, bid_amount, created_at FROM bids
-WHERE product_id = $1
-ORDER BY bid_amount DESC
+SELECT b.id, b.bidder_id, u.username, b.bid_amount, b.quantity, b.created_at
+FROM bids b
+JOIN users u ON u.id = b.bidder_id
+WHERE b.product_id = $1
+    AND ($2::timestamptz IS NULL
+        OR (b.created_at, b.id) < ($2::timestamptz, $3::uuid))
+ORDER BY b.created_at DESC, b.id DESC
+LIMIT $4
 `
 
-func (q *Queries) GetBidsByProductId(ctx context.Context, productID uuid.UUID) ([]Bid, error) {
-	rows, err := q.db.Query(ctx, getBidsByProductId, productID)
+type GetBidsByProductIdParams struct {
+	ProductID       uuid.UUID          `json:"product_id"`
+	CursorCreatedAt pgtype.Timestamptz `json:"cursor_created_at"`
+	CursorID        pgtype.UUID        `json:"cursor_id"`
+	PageSize        int32              `json:"page_size"`
+}
+
+type GetBidsByProductIdRow struct {
+	ID        uuid.UUID    `json:"id"`
+	BidderID  uuid.UUID    `json:"bidder_id"`
+	Username  string       `json:"username"`
+	BidAmount money.Amount `json:"bid_amount"`
+	Quantity  int32        `json:"quantity"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+func (q *Queries) GetBidsByProductId(ctx context.Context, arg GetBidsByProductIdParams) ([]GetBidsByProductIdRow, error) {
+	rows, err := q.db.Query(ctx, getBidsByProductId,
+		arg.ProductID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetBidsByProductIdRow
+	for rows.Next() {
+		var i GetBidsByProductIdRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.BidderID,
+			&i.Username,
+			&i.BidAmount,
+			&i.Quantity,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getBidsByProductIdSinceSeq = `-- name: GetBidsByProductIdSinceSeq :many
+SELECT id, product_id, bidder_id, bid_amount, created_at, quantity, seq FROM bids
+WHERE product_id = $1 AND seq > $2
+ORDER BY seq
+`
+
+type GetBidsByProductIdSinceSeqParams struct {
+	ProductID uuid.UUID   `json:"product_id"`
+	Seq       pgtype.Int8 `json:"seq"`
+}
+
+func (q *Queries) GetBidsByProductIdSinceSeq(ctx context.Context, arg GetBidsByProductIdSinceSeqParams) ([]Bid, error) {
+	rows, err := q.db.Query(ctx, getBidsByProductIdSinceSeq, arg.ProductID, arg.Seq)
 	if err != nil {
 		return nil, err
 	}
@@ -75,6 +177,8 @@ func (q *Queries) GetBidsByProductId(ctx context.Context, productID uuid.UUID) (
 			&i.BidderID,
 			&i.BidAmount,
 			&i.CreatedAt,
+			&i.Quantity,
+			&i.Seq,
 		); err != nil {
 			return nil, err
 		}
@@ -87,26 +191,87 @@ func (q *Queries) GetBidsByProductId(ctx context.Context, productID uuid.UUID) (
 }
 
 const getBidsByUserId = `-- name: GetBidsByUserId :many
-SELECT id, product_id, bidder_id, bid_amount, created_at FROM bids
-WHERE bidder_id = $1
-LIMIT 10
+SELECT id, product_id, product_name, auction_type, bid_amount, quantity, created_at, outcome
+FROM (
+    SELECT b.id, b.product_id, p.product_name, p.auction_type, b.bid_amount, b.quantity, b.created_at,
+        (CASE
+            WHEN p.settled_at IS NULL THEN 'active'
+            WHEN w.bidder_id = b.bidder_id
+                OR EXISTS (SELECT 1 FROM unit_awards ua WHERE ua.product_id = b.product_id AND ua.bidder_id = b.bidder_id)
+                THEN 'won'
+            ELSE 'lost'
+        END)::text AS outcome
+    FROM bids b
+    JOIN products p ON p.id = b.product_id
+    LEFT JOIN bids w ON w.id = p.winning_bid_id
+    WHERE b.bidder_id = $1
+        AND p.auction_type NOT IN ('sealed_first_price', 'vickrey')
+
+    UNION ALL
+
+    -- envelope fechado: o lance fica em sealed_bids, e a linha em bids é só
+    -- a compra gravada para o vencedor na liquidação
+    SELECT s.id, s.product_id, p.product_name, p.auction_type, s.bid_amount, 1 AS quantity, s.updated_at AS created_at,
+        (CASE
+            WHEN p.settled_at IS NULL THEN 'active'
+            WHEN w.bidder_id = s.bidder_id THEN 'won'
+            ELSE 'lost'
+        END)::text AS outcome
+    FROM sealed_bids s
+    JOIN products p ON p.id = s.product_id
+    LEFT JOIN bids w ON w.id = p.winning_bid_id
+    WHERE s.bidder_id = $1
+) my_bids
+WHERE ($2::text IS NULL OR outcome = $2::text)
+    AND ($3::timestamptz IS NULL
+        OR (created_at, id) < ($3::timestamptz, $4::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $5
 `
 
-func (q *Queries) GetBidsByUserId(ctx context.Context, bidderID uuid.UUID) ([]Bid, error) {
-	rows, err := q.db.Query(ctx, getBidsByUserId, bidderID)
+type GetBidsByUserIdParams struct {
+	BidderID        uuid.UUID          `json:"bidder_id"`
+	Outcome         pgtype.Text        `json:"outcome"`
+	CursorCreatedAt pgtype.Timestamptz `json:"cursor_created_at"`
+	CursorID        pgtype.UUID        `json:"cursor_id"`
+	PageSize        int32              `json:"page_size"`
+}
+
+type GetBidsByUserIdRow struct {
+	ID          uuid.UUID    `json:"id"`
+	ProductID   uuid.UUID    `json:"product_id"`
+	ProductName string       `json:"product_name"`
+	AuctionType auction.Type `json:"auction_type"`
+	BidAmount   money.Amount `json:"bid_amount"`
+	Quantity    int32        `json:"quantity"`
+	CreatedAt   time.Time    `json:"created_at"`
+	Outcome     string       `json:"outcome"`
+}
+
+func (q *Queries) GetBidsByUserId(ctx context.Context, arg GetBidsByUserIdParams) ([]GetBidsByUserIdRow, error) {
+	rows, err := q.db.Query(ctx, getBidsByUserId,
+		arg.BidderID,
+		arg.Outcome,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Bid
+	var items []GetBidsByUserIdRow
 	for rows.Next() {
-		var i Bid
+		var i GetBidsByUserIdRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.ProductID,
-			&i.BidderID,
+			&i.ProductName,
+			&i.AuctionType,
 			&i.BidAmount,
+			&i.Quantity,
 			&i.CreatedAt,
+			&i.Outcome,
 		); err != nil {
 			return nil, err
 		}
@@ -118,8 +283,35 @@ func (q *Queries) GetBidsByUserId(ctx context.Context, bidderID uuid.UUID) ([]Bi
 	return items, nil
 }
 
+const getHighestBidByProductAndBidder = `-- name: GetHighestBidByProductAndBidder :one
+SELECT id, product_id, bidder_id, bid_amount, created_at, quantity, seq FROM bids
+WHERE product_id = $1 AND bidder_id = $2
+ORDER BY bid_amount DESC
+LIMIT 1
+`
+
+type GetHighestBidByProductAndBidderParams struct {
+	ProductID uuid.UUID `json:"product_id"`
+	BidderID  uuid.UUID `json:"bidder_id"`
+}
+
+func (q *Queries) GetHighestBidByProductAndBidder(ctx context.Context, arg GetHighestBidByProductAndBidderParams) (Bid, error) {
+	row := q.db.QueryRow(ctx, getHighestBidByProductAndBidder, arg.ProductID, arg.BidderID)
+	var i Bid
+	err := row.Scan(
+		&i.ID,
+		&i.ProductID,
+		&i.BidderID,
+		&i.BidAmount,
+		&i.CreatedAt,
+		&i.Quantity,
+		&i.Seq,
+	)
+	return i, err
+}
+
 const getHighestBidByProductId = `-- name: GetHighestBidByProductId :one
-SELECT id, product_id, bidder_id, bid_amount, created_at FROM bids
+SELECT id, product_id, bidder_id, bid_amount, created_at, quantity, seq FROM bids
 WHERE product_id = $1
 ORDER BY bid_amount DESC
 LIMIT 1
@@ -134,6 +326,42 @@ func (q *Queries) GetHighestBidByProductId(ctx context.Context, productID uuid.U
 		&i.BidderID,
 		&i.BidAmount,
 		&i.CreatedAt,
+		&i.Quantity,
+		&i.Seq,
 	)
 	return i, err
 }
+
+const getLatestBidsByProductId = `-- name: GetLatestBidsByProductId :many
+SELECT DISTINCT ON (bidder_id) * FROM bids
+WHERE product_id = $1
+ORDER BY bidder_id, created_at DESC
+`
+
+func (q *Queries) GetLatestBidsByProductId(ctx context.Context, productID uuid.UUID) ([]Bid, error) {
+	rows, err := q.db.Query(ctx, getLatestBidsByProductId, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Bid
+	for rows.Next() {
+		var i Bid
+		if err := rows.Scan(
+			&i.ID,
+			&i.ProductID,
+			&i.BidderID,
+			&i.BidAmount,
+			&i.CreatedAt,
+			&i.Quantity,
+			&i.Seq,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,16 @@
+-- Write your migrate up statements here
+
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS winning_bid_id UUID REFERENCES bids (id),
+    ADD COLUMN IF NOT EXISTS closing_price FLOAT,
+    ADD COLUMN IF NOT EXISTS settled_at TIMESTAMPTZ;
+
+---- create above / drop below ----
+
+ALTER TABLE products
+    DROP COLUMN IF EXISTS settled_at,
+    DROP COLUMN IF EXISTS closing_price,
+    DROP COLUMN IF EXISTS winning_bid_id;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
@@ -0,0 +1,24 @@
+-- Write your migrate up statements here
+
+-- valores monetários passam a ser guardados em centavos (BIGINT). O cast para
+-- NUMERIC antes de multiplicar evita levar o erro do FLOAT para os dados novos.
+ALTER TABLE products
+    ALTER COLUMN baseprice TYPE BIGINT USING round(baseprice::NUMERIC * 100)::BIGINT,
+    ALTER COLUMN closing_price TYPE BIGINT USING round(closing_price::NUMERIC * 100)::BIGINT,
+    ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'BRL';
+
+ALTER TABLE bids
+    ALTER COLUMN bid_amount TYPE BIGINT USING round(bid_amount::NUMERIC * 100)::BIGINT;
+
+---- create above / drop below ----
+
+ALTER TABLE bids
+    ALTER COLUMN bid_amount TYPE FLOAT USING bid_amount / 100.0;
+
+ALTER TABLE products
+    DROP COLUMN IF EXISTS currency,
+    ALTER COLUMN closing_price TYPE FLOAT USING closing_price / 100.0,
+    ALTER COLUMN baseprice TYPE FLOAT USING baseprice / 100.0;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
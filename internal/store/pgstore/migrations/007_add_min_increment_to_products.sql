@@ -0,0 +1,12 @@
+-- Write your migrate up statements here
+
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS min_increment JSONB NOT NULL DEFAULT '{"kind": "fixed", "amount": "0.01"}';
+
+---- create above / drop below ----
+
+ALTER TABLE products
+    DROP COLUMN IF EXISTS min_increment;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
@@ -0,0 +1,21 @@
+-- Write your migrate up statements here
+
+CREATE TABLE IF NOT EXISTS max_bids (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+
+    product_id UUID NOT NULL REFERENCES products (id),
+    bidder_id UUID NOT NULL REFERENCES users (id),
+    max_amount BIGINT NOT NULL,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    UNIQUE (product_id, bidder_id)
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS max_bids;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
@@ -0,0 +1,28 @@
+-- Write your migrate up statements here
+
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS auction_start TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'live'
+        CHECK (status IN ('upcoming', 'live', 'ended', 'sold'));
+
+UPDATE products
+SET auction_start = created_at,
+    status = CASE
+        WHEN is_sold THEN 'sold'
+        WHEN settled_at IS NOT NULL THEN 'ended'
+        ELSE 'live'
+    END;
+
+CREATE INDEX IF NOT EXISTS products_upcoming_auction_start_idx
+    ON products (auction_start) WHERE status = 'upcoming';
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS products_upcoming_auction_start_idx;
+
+ALTER TABLE products
+    DROP COLUMN IF EXISTS status,
+    DROP COLUMN IF EXISTS auction_start;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
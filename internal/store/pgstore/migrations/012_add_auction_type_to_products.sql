@@ -0,0 +1,15 @@
+-- Write your migrate up statements here
+
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS auction_type TEXT NOT NULL DEFAULT 'english'
+        CONSTRAINT products_auction_type_check CHECK (auction_type IN ('english', 'dutch')),
+    ADD COLUMN IF NOT EXISTS dutch_schedule JSONB;
+
+---- create above / drop below ----
+
+ALTER TABLE products
+    DROP COLUMN IF EXISTS dutch_schedule,
+    DROP COLUMN IF EXISTS auction_type;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
@@ -0,0 +1,31 @@
+-- Write your migrate up statements here
+
+ALTER TABLE products
+    DROP CONSTRAINT IF EXISTS products_auction_type_check,
+    ADD CONSTRAINT products_auction_type_check
+        CHECK (auction_type IN ('english', 'dutch', 'sealed_first_price', 'vickrey'));
+
+CREATE TABLE IF NOT EXISTS sealed_bids (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+
+    product_id UUID NOT NULL REFERENCES products (id),
+    bidder_id UUID NOT NULL REFERENCES users (id),
+    bid_amount BIGINT NOT NULL,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    UNIQUE (product_id, bidder_id)
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS sealed_bids;
+
+ALTER TABLE products
+    DROP CONSTRAINT IF EXISTS products_auction_type_check,
+    ADD CONSTRAINT products_auction_type_check
+        CHECK (auction_type IN ('english', 'dutch'));
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
@@ -0,0 +1,37 @@
+-- Write your migrate up statements here
+
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS quantity INTEGER NOT NULL DEFAULT 1 CHECK (quantity > 0),
+    ADD COLUMN IF NOT EXISTS pricing_rule TEXT NOT NULL DEFAULT 'uniform'
+        CHECK (pricing_rule IN ('uniform', 'pay_as_bid'));
+
+ALTER TABLE bids
+    ADD COLUMN IF NOT EXISTS quantity INTEGER NOT NULL DEFAULT 1 CHECK (quantity > 0);
+
+CREATE TABLE IF NOT EXISTS unit_awards (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+
+    product_id UUID NOT NULL REFERENCES products (id),
+    bid_id UUID NOT NULL REFERENCES bids (id),
+    bidder_id UUID NOT NULL REFERENCES users (id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    unit_price BIGINT NOT NULL,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    UNIQUE (product_id, bidder_id)
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS unit_awards;
+
+ALTER TABLE bids
+    DROP COLUMN IF EXISTS quantity;
+
+ALTER TABLE products
+    DROP COLUMN IF EXISTS pricing_rule,
+    DROP COLUMN IF EXISTS quantity;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
@@ -0,0 +1,22 @@
+-- Write your migrate up statements here
+
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS event_seq BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE bids
+    ADD COLUMN IF NOT EXISTS seq BIGINT;
+
+CREATE INDEX IF NOT EXISTS bids_product_id_seq_idx ON bids (product_id, seq);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS bids_product_id_seq_idx;
+
+ALTER TABLE bids
+    DROP COLUMN IF EXISTS seq;
+
+ALTER TABLE products
+    DROP COLUMN IF EXISTS event_seq;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
@@ -0,0 +1,27 @@
+-- Write your migrate up statements here
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id UUID NOT NULL REFERENCES users (id),
+    idempotency_key TEXT NOT NULL,
+
+    product_id UUID NOT NULL REFERENCES products (id),
+    request_hash TEXT NOT NULL,
+
+    -- nulos enquanto o pedido está sendo processado
+    status_code INTEGER,
+    response_body JSONB,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    completed_at TIMESTAMPTZ,
+
+    PRIMARY KEY (user_id, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_created_at_idx ON idempotency_keys (created_at);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS idempotency_keys;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
@@ -0,0 +1,12 @@
+-- Write your migrate up statements here
+
+ALTER TABLE bids
+    ALTER COLUMN created_at SET DEFAULT clock_timestamp();
+
+---- create above / drop below ----
+
+ALTER TABLE bids
+    ALTER COLUMN created_at SET DEFAULT now();
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
@@ -8,26 +8,74 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mauvalente/go-bid/internal/auction"
+	"github.com/mauvalente/go-bid/internal/money"
 )
 
 type Bid struct {
-	ID        uuid.UUID `json:"id"`
-	ProductID uuid.UUID `json:"product_id"`
-	BidderID  uuid.UUID `json:"bidder_id"`
-	BidAmount float64   `json:"bid_amount"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        uuid.UUID    `json:"id"`
+	ProductID uuid.UUID    `json:"product_id"`
+	BidderID  uuid.UUID    `json:"bidder_id"`
+	BidAmount money.Amount `json:"bid_amount"`
+	CreatedAt time.Time    `json:"created_at"`
+	Quantity  int32        `json:"quantity"`
+	Seq       pgtype.Int8  `json:"seq"`
+}
+
+type IdempotencyKey struct {
+	UserID         uuid.UUID          `json:"user_id"`
+	IdempotencyKey string             `json:"idempotency_key"`
+	ProductID      uuid.UUID          `json:"product_id"`
+	RequestHash    string             `json:"request_hash"`
+	StatusCode     pgtype.Int4        `json:"status_code"`
+	ResponseBody   []byte             `json:"response_body"`
+	CreatedAt      time.Time          `json:"created_at"`
+	CompletedAt    pgtype.Timestamptz `json:"completed_at"`
+}
+
+type MaxBid struct {
+	ID        uuid.UUID    `json:"id"`
+	ProductID uuid.UUID    `json:"product_id"`
+	BidderID  uuid.UUID    `json:"bidder_id"`
+	MaxAmount money.Amount `json:"max_amount"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
 }
 
 type Product struct {
-	ID          uuid.UUID `json:"id"`
-	SellerID    uuid.UUID `json:"seller_id"`
-	ProductName string    `json:"product_name"`
-	Description string    `json:"description"`
-	Baseprice   float64   `json:"baseprice"`
-	AuctionEnd  time.Time `json:"auction_end"`
-	IsSold      bool      `json:"is_sold"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID            uuid.UUID              `json:"id"`
+	SellerID      uuid.UUID              `json:"seller_id"`
+	ProductName   string                 `json:"product_name"`
+	Description   string                 `json:"description"`
+	Baseprice     money.Amount           `json:"baseprice"`
+	AuctionEnd    time.Time              `json:"auction_end"`
+	IsSold        bool                   `json:"is_sold"`
+	CreatedAt     time.Time              `json:"created_at"`
+	UpdatedAt     time.Time              `json:"updated_at"`
+	WinningBidID  pgtype.UUID            `json:"winning_bid_id"`
+	ClosingPrice  *money.Amount          `json:"closing_price"`
+	SettledAt     pgtype.Timestamptz     `json:"settled_at"`
+	Currency      money.Currency         `json:"currency"`
+	MinIncrement  auction.IncrementRule  `json:"min_increment"`
+	ReservePrice  *money.Amount          `json:"reserve_price"`
+	BuyNowPrice   *money.Amount          `json:"buy_now_price"`
+	AuctionStart  time.Time              `json:"auction_start"`
+	Status        string                 `json:"status"`
+	AuctionType   auction.Type           `json:"auction_type"`
+	DutchSchedule *auction.DutchSchedule `json:"dutch_schedule"`
+	Quantity      int32                  `json:"quantity"`
+	PricingRule   auction.PricingRule    `json:"pricing_rule"`
+	EventSeq      int64                  `json:"event_seq"`
+}
+
+type SealedBid struct {
+	ID        uuid.UUID    `json:"id"`
+	ProductID uuid.UUID    `json:"product_id"`
+	BidderID  uuid.UUID    `json:"bidder_id"`
+	BidAmount money.Amount `json:"bid_amount"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
 }
 
 type Session struct {
@@ -36,6 +84,16 @@ type Session struct {
 	Expiry time.Time `json:"expiry"`
 }
 
+type UnitAward struct {
+	ID        uuid.UUID    `json:"id"`
+	ProductID uuid.UUID    `json:"product_id"`
+	BidID     uuid.UUID    `json:"bid_id"`
+	BidderID  uuid.UUID    `json:"bidder_id"`
+	Quantity  int32        `json:"quantity"`
+	UnitPrice money.Amount `json:"unit_price"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
 type User struct {
 	ID           uuid.UUID `json:"id"`
 	Username     string    `json:"username"`
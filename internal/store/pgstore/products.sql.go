@@ -10,28 +10,44 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mauvalente/go-bid/internal/auction"
+	"github.com/mauvalente/go-bid/internal/money"
 )
 
 const createProduct = `-- name: CreateProduct :one
 INSERT INTO products (
     seller_id, product_name, description,
-    baseprice, auction_end, is_sold
+    baseprice, auction_end, is_sold, currency,
+    min_increment, reserve_price, buy_now_price,
+    auction_start, status, auction_type, dutch_schedule,
+    quantity, pricing_rule
 ) VALUES (
-    $1,$2,$3,$4,$5,$6
+    $1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16
 )
-RETURNING id
+RETURNING id, seller_id, product_name, description, baseprice, auction_end, is_sold, created_at, updated_at, winning_bid_id, closing_price, settled_at, currency, min_increment, reserve_price, buy_now_price, auction_start, status, auction_type, dutch_schedule, quantity, pricing_rule, event_seq
 `
 
 type CreateProductParams struct {
-	SellerID    uuid.UUID `json:"seller_id"`
-	ProductName string    `json:"product_name"`
-	Description string    `json:"description"`
-	Baseprice   float64   `json:"baseprice"`
-	AuctionEnd  time.Time `json:"auction_end"`
-	IsSold      bool      `json:"is_sold"`
+	SellerID      uuid.UUID              `json:"seller_id"`
+	ProductName   string                 `json:"product_name"`
+	Description   string                 `json:"description"`
+	Baseprice     money.Amount           `json:"baseprice"`
+	AuctionEnd    time.Time              `json:"auction_end"`
+	IsSold        bool                   `json:"is_sold"`
+	Currency      money.Currency         `json:"currency"`
+	MinIncrement  auction.IncrementRule  `json:"min_increment"`
+	ReservePrice  *money.Amount          `json:"reserve_price"`
+	BuyNowPrice   *money.Amount          `json:"buy_now_price"`
+	AuctionStart  time.Time              `json:"auction_start"`
+	Status        string                 `json:"status"`
+	AuctionType   auction.Type           `json:"auction_type"`
+	DutchSchedule *auction.DutchSchedule `json:"dutch_schedule"`
+	Quantity      int32                  `json:"quantity"`
+	PricingRule   auction.PricingRule    `json:"pricing_rule"`
 }
 
-func (q *Queries) CreateProduct(ctx context.Context, arg CreateProductParams) (uuid.UUID, error) {
+func (q *Queries) CreateProduct(ctx context.Context, arg CreateProductParams) (Product, error) {
 	row := q.db.QueryRow(ctx, createProduct,
 		arg.SellerID,
 		arg.ProductName,
@@ -39,15 +55,49 @@ func (q *Queries) CreateProduct(ctx context.Context, arg CreateProductParams) (u
 		arg.Baseprice,
 		arg.AuctionEnd,
 		arg.IsSold,
+		arg.Currency,
+		arg.MinIncrement,
+		arg.ReservePrice,
+		arg.BuyNowPrice,
+		arg.AuctionStart,
+		arg.Status,
+		arg.AuctionType,
+		arg.DutchSchedule,
+		arg.Quantity,
+		arg.PricingRule,
 	)
-	var id uuid.UUID
-	err := row.Scan(&id)
-	return id, err
+	var i Product
+	err := row.Scan(
+		&i.ID,
+		&i.SellerID,
+		&i.ProductName,
+		&i.Description,
+		&i.Baseprice,
+		&i.AuctionEnd,
+		&i.IsSold,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.WinningBidID,
+		&i.ClosingPrice,
+		&i.SettledAt,
+		&i.Currency,
+		&i.MinIncrement,
+		&i.ReservePrice,
+		&i.BuyNowPrice,
+		&i.AuctionStart,
+		&i.Status,
+		&i.AuctionType,
+		&i.DutchSchedule,
+		&i.Quantity,
+		&i.PricingRule,
+		&i.EventSeq,
+	)
+	return i, err
 }
 
 const getAllAvailableProducts = `-- name: GetAllAvailableProducts :many
-SELECT id, seller_id, product_name, description, baseprice, auction_end, is_sold, created_at, updated_at FROM products
-WHERE auction_end > now()
+SELECT id, seller_id, product_name, description, baseprice, auction_end, is_sold, created_at, updated_at, winning_bid_id, closing_price, settled_at, currency, min_increment, reserve_price, buy_now_price, auction_start, status, auction_type, dutch_schedule, quantity, pricing_rule, event_seq FROM products
+WHERE auction_end > now() AND settled_at IS NULL
 `
 
 func (q *Queries) GetAllAvailableProducts(ctx context.Context) ([]Product, error) {
@@ -69,6 +119,20 @@ func (q *Queries) GetAllAvailableProducts(ctx context.Context) ([]Product, error
 			&i.IsSold,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.WinningBidID,
+			&i.ClosingPrice,
+			&i.SettledAt,
+			&i.Currency,
+			&i.MinIncrement,
+			&i.ReservePrice,
+			&i.BuyNowPrice,
+			&i.AuctionStart,
+			&i.Status,
+			&i.AuctionType,
+			&i.DutchSchedule,
+			&i.Quantity,
+			&i.PricingRule,
+			&i.EventSeq,
 		); err != nil {
 			return nil, err
 		}
@@ -80,8 +144,33 @@ func (q *Queries) GetAllAvailableProducts(ctx context.Context) ([]Product, error
 	return items, nil
 }
 
+const getExpiredUnsettledProductIds = `-- name: GetExpiredUnsettledProductIds :many
+SELECT id FROM products
+WHERE auction_end <= now() AND settled_at IS NULL
+`
+
+func (q *Queries) GetExpiredUnsettledProductIds(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := q.db.Query(ctx, getExpiredUnsettledProductIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getProductById = `-- name: GetProductById :one
-SELECT id, seller_id, product_name, description, baseprice, auction_end, is_sold, created_at, updated_at FROM products
+SELECT id, seller_id, product_name, description, baseprice, auction_end, is_sold, created_at, updated_at, winning_bid_id, closing_price, settled_at, currency, min_increment, reserve_price, buy_now_price, auction_start, status, auction_type, dutch_schedule, quantity, pricing_rule, event_seq FROM products
 WHERE id = $1
 `
 
@@ -98,6 +187,133 @@ func (q *Queries) GetProductById(ctx context.Context, id uuid.UUID) (Product, er
 		&i.IsSold,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.WinningBidID,
+		&i.ClosingPrice,
+		&i.SettledAt,
+		&i.Currency,
+		&i.MinIncrement,
+		&i.ReservePrice,
+		&i.BuyNowPrice,
+		&i.AuctionStart,
+		&i.Status,
+		&i.AuctionType,
+		&i.DutchSchedule,
+		&i.Quantity,
+		&i.PricingRule,
+		&i.EventSeq,
+	)
+	return i, err
+}
+
+const getProductByIdForUpdate = `-- name: GetProductByIdForUpdate :one
+SELECT id, seller_id, product_name, description, baseprice, auction_end, is_sold, created_at, updated_at, winning_bid_id, closing_price, settled_at, currency, min_increment, reserve_price, buy_now_price, auction_start, status, auction_type, dutch_schedule, quantity, pricing_rule, event_seq FROM products
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetProductByIdForUpdate(ctx context.Context, id uuid.UUID) (Product, error) {
+	row := q.db.QueryRow(ctx, getProductByIdForUpdate, id)
+	var i Product
+	err := row.Scan(
+		&i.ID,
+		&i.SellerID,
+		&i.ProductName,
+		&i.Description,
+		&i.Baseprice,
+		&i.AuctionEnd,
+		&i.IsSold,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.WinningBidID,
+		&i.ClosingPrice,
+		&i.SettledAt,
+		&i.Currency,
+		&i.MinIncrement,
+		&i.ReservePrice,
+		&i.BuyNowPrice,
+		&i.AuctionStart,
+		&i.Status,
+		&i.AuctionType,
+		&i.DutchSchedule,
+		&i.Quantity,
+		&i.PricingRule,
+		&i.EventSeq,
 	)
 	return i, err
 }
+
+const settleProduct = `-- name: SettleProduct :execrows
+UPDATE products
+SET is_sold = $2,
+    winning_bid_id = $3,
+    closing_price = $4,
+    status = CASE WHEN $2 THEN 'sold' ELSE 'ended' END,
+    settled_at = now(),
+    updated_at = now()
+WHERE id = $1 AND settled_at IS NULL
+`
+
+type SettleProductParams struct {
+	ID           uuid.UUID     `json:"id"`
+	IsSold       bool          `json:"is_sold"`
+	WinningBidID pgtype.UUID   `json:"winning_bid_id"`
+	ClosingPrice *money.Amount `json:"closing_price"`
+}
+
+func (q *Queries) SettleProduct(ctx context.Context, arg SettleProductParams) (int64, error) {
+	result, err := q.db.Exec(ctx, settleProduct,
+		arg.ID,
+		arg.IsSold,
+		arg.WinningBidID,
+		arg.ClosingPrice,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const startDueAuctions = `-- name: StartDueAuctions :many
+UPDATE products
+SET status = 'live',
+    updated_at = now()
+WHERE status = 'upcoming' AND auction_start <= now()
+RETURNING id
+`
+
+func (q *Queries) StartDueAuctions(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := q.db.Query(ctx, startDueAuctions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateProductAuctionEnd = `-- name: UpdateProductAuctionEnd :exec
+UPDATE products
+SET auction_end = $2,
+    updated_at = now()
+WHERE id = $1
+`
+
+type UpdateProductAuctionEndParams struct {
+	ID         uuid.UUID `json:"id"`
+	AuctionEnd time.Time `json:"auction_end"`
+}
+
+func (q *Queries) UpdateProductAuctionEnd(ctx context.Context, arg UpdateProductAuctionEndParams) error {
+	_, err := q.db.Exec(ctx, updateProductAuctionEnd, arg.ID, arg.AuctionEnd)
+	return err
+}
@@ -1,6 +1,6 @@
 -- name: CreateBid :one
-INSERT INTO bids ("product_id", "bidder_id", "bid_amount")
-VALUES ($1, $2, $3)
+INSERT INTO bids ("product_id", "bidder_id", "bid_amount", "quantity")
+VALUES ($1, $2, $3, $4)
 RETURNING *;
 
 -- name: GetBidById :one
@@ -8,9 +8,14 @@ SELECT * FROM bids
 WHERE id = $1;
 
 -- name: GetBidsByProductId :many
-SELECT * FROM bids
-WHERE product_id = $1
-ORDER BY bid_amount DESC;
+SELECT b.id, b.bidder_id, u.username, b.bid_amount, b.quantity, b.created_at
+FROM bids b
+JOIN users u ON u.id = b.bidder_id
+WHERE b.product_id = sqlc.arg('product_id')
+    AND (sqlc.narg('cursor_created_at')::timestamptz IS NULL
+        OR (b.created_at, b.id) < (sqlc.narg('cursor_created_at')::timestamptz, sqlc.narg('cursor_id')::uuid))
+ORDER BY b.created_at DESC, b.id DESC
+LIMIT sqlc.arg('page_size');
 
 -- name: GetHighestBidByProductId :one
 SELECT * FROM bids
@@ -18,8 +23,64 @@ WHERE product_id = $1
 ORDER BY bid_amount DESC
 LIMIT 1;
 
+-- name: GetLatestBidsByProductId :many
+SELECT DISTINCT ON (bidder_id) * FROM bids
+WHERE product_id = $1
+ORDER BY bidder_id, created_at DESC;
+
 -- name: GetBidsByUserId :many
+SELECT id, product_id, product_name, auction_type, bid_amount, quantity, created_at, outcome
+FROM (
+    SELECT b.id, b.product_id, p.product_name, p.auction_type, b.bid_amount, b.quantity, b.created_at,
+        (CASE
+            WHEN p.settled_at IS NULL THEN 'active'
+            WHEN w.bidder_id = b.bidder_id
+                OR EXISTS (SELECT 1 FROM unit_awards ua WHERE ua.product_id = b.product_id AND ua.bidder_id = b.bidder_id)
+                THEN 'won'
+            ELSE 'lost'
+        END)::text AS outcome
+    FROM bids b
+    JOIN products p ON p.id = b.product_id
+    LEFT JOIN bids w ON w.id = p.winning_bid_id
+    WHERE b.bidder_id = sqlc.arg('bidder_id')
+        AND p.auction_type NOT IN ('sealed_first_price', 'vickrey')
+
+    UNION ALL
+
+    -- envelope fechado: o lance fica em sealed_bids, e a linha em bids é só
+    -- a compra gravada para o vencedor na liquidação
+    SELECT s.id, s.product_id, p.product_name, p.auction_type, s.bid_amount, 1 AS quantity, s.updated_at AS created_at,
+        (CASE
+            WHEN p.settled_at IS NULL THEN 'active'
+            WHEN w.bidder_id = s.bidder_id THEN 'won'
+            ELSE 'lost'
+        END)::text AS outcome
+    FROM sealed_bids s
+    JOIN products p ON p.id = s.product_id
+    LEFT JOIN bids w ON w.id = p.winning_bid_id
+    WHERE s.bidder_id = sqlc.arg('bidder_id')
+) my_bids
+WHERE (sqlc.narg('outcome')::text IS NULL OR outcome = sqlc.narg('outcome')::text)
+    AND (sqlc.narg('cursor_created_at')::timestamptz IS NULL
+        OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamptz, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: CountBidsByProductId :one
+SELECT COUNT(*) FROM bids
+WHERE product_id = $1;
+
+-- name: CountBiddersByProductId :one
+SELECT COUNT(DISTINCT bidder_id) FROM bids
+WHERE product_id = $1;
+
+-- name: GetHighestBidByProductAndBidder :one
 SELECT * FROM bids
-WHERE bidder_id = $1
-LIMIT 10;
+WHERE product_id = $1 AND bidder_id = $2
+ORDER BY bid_amount DESC
+LIMIT 1;
 
+-- name: GetBidsByProductIdSinceSeq :many
+SELECT * FROM bids
+WHERE product_id = $1 AND seq > $2
+ORDER BY seq;
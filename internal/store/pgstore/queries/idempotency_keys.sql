@@ -0,0 +1,21 @@
+-- name: CreateIdempotencyKey :execrows
+INSERT INTO idempotency_keys ("user_id", "idempotency_key", "product_id", "request_hash")
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id, idempotency_key) DO NOTHING;
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_keys
+WHERE user_id = $1 AND idempotency_key = $2;
+
+-- name: CompleteIdempotencyKey :exec
+UPDATE idempotency_keys
+SET status_code = $3, response_body = $4, completed_at = now()
+WHERE user_id = $1 AND idempotency_key = $2;
+
+-- name: DeleteIdempotencyKey :exec
+DELETE FROM idempotency_keys
+WHERE user_id = $1 AND idempotency_key = $2;
+
+-- name: DeleteExpiredIdempotencyKeys :execrows
+DELETE FROM idempotency_keys
+WHERE created_at < $1;
@@ -1,11 +1,14 @@
 -- name: CreateProduct :one
 INSERT INTO products (
     seller_id, product_name, description,
-    baseprice, auction_end, is_sold
+    baseprice, auction_end, is_sold, currency,
+    min_increment, reserve_price, buy_now_price,
+    auction_start, status, auction_type, dutch_schedule,
+    quantity, pricing_rule
 ) VALUES (
-    $1,$2,$3,$4,$5,$6
+    $1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16
 )
-RETURNING id;
+RETURNING *;
 
 
 -- name: GetProductById :one
@@ -15,4 +18,41 @@ WHERE id = $1;
 
 -- name: GetAllAvailableProducts :many
 SELECT * FROM products
-WHERE auction_end > now();
+WHERE auction_end > now() AND settled_at IS NULL;
+
+
+-- name: GetProductByIdForUpdate :one
+SELECT * FROM products
+WHERE id = $1
+FOR UPDATE;
+
+
+-- name: SettleProduct :execrows
+UPDATE products
+SET is_sold = $2,
+    winning_bid_id = $3,
+    closing_price = $4,
+    status = CASE WHEN $2 THEN 'sold' ELSE 'ended' END,
+    settled_at = now(),
+    updated_at = now()
+WHERE id = $1 AND settled_at IS NULL;
+
+
+-- name: GetExpiredUnsettledProductIds :many
+SELECT id FROM products
+WHERE auction_end <= now() AND settled_at IS NULL;
+
+
+-- name: UpdateProductAuctionEnd :exec
+UPDATE products
+SET auction_end = $2,
+    updated_at = now()
+WHERE id = $1;
+
+
+-- name: StartDueAuctions :many
+UPDATE products
+SET status = 'live',
+    updated_at = now()
+WHERE status = 'upcoming' AND auction_start <= now()
+RETURNING id;
@@ -0,0 +1,20 @@
+-- name: UpsertSealedBid :one
+INSERT INTO sealed_bids ("product_id", "bidder_id", "bid_amount")
+VALUES ($1, $2, $3)
+ON CONFLICT (product_id, bidder_id)
+DO UPDATE SET bid_amount = EXCLUDED.bid_amount, updated_at = now()
+RETURNING *;
+
+-- name: GetTopSealedBids :many
+SELECT * FROM sealed_bids
+WHERE product_id = $1
+ORDER BY bid_amount DESC, updated_at ASC
+LIMIT 2;
+
+-- name: GetSealedBidByProductAndBidder :one
+SELECT * FROM sealed_bids
+WHERE product_id = $1 AND bidder_id = $2;
+
+-- name: CountSealedBidsByProductId :one
+SELECT COUNT(*) FROM sealed_bids
+WHERE product_id = $1;
@@ -0,0 +1,8 @@
+-- name: CreateUnitAward :exec
+INSERT INTO unit_awards ("product_id", "bid_id", "bidder_id", "quantity", "unit_price")
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: GetUnitAwardsByProductId :many
+SELECT * FROM unit_awards
+WHERE product_id = $1
+ORDER BY unit_price DESC, created_at ASC;
@@ -0,0 +1,24 @@
+package bid
+
+import (
+	"context"
+
+	"github.com/mauvalente/go-bid/internal/money"
+	"github.com/mauvalente/go-bid/internal/validator"
+)
+
+type PlaceBidReq struct {
+	Amount    money.Amount `json:"amount"`
+	Quantity  int32        `json:"quantity"`
+	RequestId string       `json:"request_id"`
+}
+
+func (req PlaceBidReq) Valid(ctx context.Context) validator.Evaluator {
+	var eval validator.Evaluator
+
+	eval.CheckField(req.Amount > 0, "amount", "this field must be greater than 0")
+	eval.CheckField(req.Quantity >= 0, "quantity", "this field cannot be negative")
+	eval.CheckField(validator.MaxChars(req.RequestId, 64), "request_id", "this field must have at most 64 characters")
+
+	return eval
+}
@@ -6,15 +6,26 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/mauvalente/go-bid/internal/auction"
+	"github.com/mauvalente/go-bid/internal/money"
 	"github.com/mauvalente/go-bid/internal/validator"
 )
 
 type CreateProductReq struct {
-	SellerID    uuid.UUID `json:"seller_id"`
-	ProductName string    `json:"product_name"`
-	Description string    `json:"description"`
-	Baseprice   float64   `json:"baseprice"`
-	AuctionEnd  time.Time `json:"auction_end"`
+	SellerID      uuid.UUID              `json:"seller_id"`
+	ProductName   string                 `json:"product_name"`
+	Description   string                 `json:"description"`
+	Baseprice     money.Amount           `json:"baseprice"`
+	Currency      money.Currency         `json:"currency"`
+	AuctionStart  time.Time              `json:"auction_start"`
+	AuctionEnd    time.Time              `json:"auction_end"`
+	MinIncrement  auction.IncrementRule  `json:"min_increment"`
+	ReservePrice  *money.Amount          `json:"reserve_price"`
+	BuyNowPrice   *money.Amount          `json:"buy_now_price"`
+	AuctionType   auction.Type           `json:"auction_type"`
+	DutchSchedule *auction.DutchSchedule `json:"dutch_schedule"`
+	Quantity      int32                  `json:"quantity"`
+	PricingRule   auction.PricingRule    `json:"pricing_rule"`
 }
 
 const minAuctionDuration = 2 * time.Hour
@@ -30,7 +41,56 @@ func (req CreateProductReq) Valid(ctx context.Context) validator.Evaluator {
 		"description", "this field must have a length between 10 and 255")
 
 	eval.CheckField(req.Baseprice > 0, "baseprice", "this field must be greater than 0")
-	eval.CheckField(time.Until(req.AuctionEnd) >= minAuctionDuration, "auction_end", fmt.Sprintf("must be at %s two hours duration", minAuctionDuration))
+	eval.CheckField(req.Currency == "" || req.Currency.Valid(), "currency", "this field must be one of BRL, USD or EUR")
+	if req.ReservePrice != nil {
+		eval.CheckField(*req.ReservePrice >= req.Baseprice, "reserve_price", "this field must be greater than or equal to baseprice")
+	}
+	if req.BuyNowPrice != nil {
+		eval.CheckField(*req.BuyNowPrice > req.Baseprice, "buy_now_price", "this field must be greater than baseprice")
+		if req.ReservePrice != nil {
+			eval.CheckField(*req.BuyNowPrice >= *req.ReservePrice, "buy_now_price", "this field must be greater than or equal to reserve_price")
+		}
+	}
+	eval.CheckField(req.AuctionType == "" || req.AuctionType.Valid(), "auction_type", "this field must be one of english, dutch, sealed_first_price or vickrey")
+	if req.AuctionType == auction.TypeDutch {
+		// no holandês o preço só desce, então reserva e "compre já" não fazem sentido
+		eval.CheckField(req.ReservePrice == nil, "reserve_price", "this field is not allowed in dutch auctions")
+		eval.CheckField(req.BuyNowPrice == nil, "buy_now_price", "this field is not allowed in dutch auctions")
+		if req.DutchSchedule == nil {
+			eval.AddFieldError("dutch_schedule", "this field is required in dutch auctions")
+		} else if err := req.DutchSchedule.Validate(req.Baseprice); err != nil {
+			eval.AddFieldError("dutch_schedule", err.Error())
+		}
+	} else {
+		if req.AuctionType.Sealed() {
+			eval.CheckField(req.BuyNowPrice == nil, "buy_now_price", "this field is not allowed in sealed bid auctions")
+		}
+		eval.CheckField(req.DutchSchedule == nil, "dutch_schedule", "this field is only allowed in dutch auctions")
+	}
+
+	eval.CheckField(req.Quantity >= 0, "quantity", "this field cannot be negative")
+	eval.CheckField(req.PricingRule == "" || req.PricingRule.Valid(), "pricing_rule", "this field must be one of uniform or pay_as_bid")
+	if req.Quantity > 1 {
+		eval.CheckField(req.AuctionType == "" || req.AuctionType == auction.TypeEnglish, "auction_type", "multi-unit auctions must be english auctions")
+		eval.CheckField(req.ReservePrice == nil, "reserve_price", "this field is not allowed in multi-unit auctions")
+		eval.CheckField(req.BuyNowPrice == nil, "buy_now_price", "this field is not allowed in multi-unit auctions")
+	}
+
+	if req.MinIncrement.Kind != "" {
+		if err := req.MinIncrement.Validate(); err != nil {
+			eval.AddFieldError("min_increment", err.Error())
+		}
+	}
+
+	// sem auction_start o leilão começa imediatamente
+	start := time.Now()
+	if !req.AuctionStart.IsZero() {
+		eval.CheckField(!req.AuctionStart.Before(start.Add(-time.Minute)), "auction_start", "this field cannot be in the past")
+		if req.AuctionStart.After(start) {
+			start = req.AuctionStart
+		}
+	}
+	eval.CheckField(req.AuctionEnd.Sub(start) >= minAuctionDuration, "auction_end", fmt.Sprintf("must be at %s two hours duration", minAuctionDuration))
 
 	return eval
 }